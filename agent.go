@@ -0,0 +1,200 @@
+package main
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+// Agent plays the game in place of a human. It observes the match,
+// e.g. stations, graphs and the budget of its player, and decides on the next action.
+type Agent interface {
+	Name() string
+
+	// Act returns the next action of the player, or false if the agent wants to pass.
+	Act(match *Match, player *Player) (Action, bool)
+}
+
+// defaultVersusBot plays the opponents in a versus game, unless another bot is picked on launch
+const defaultVersusBot = "lookahead"
+
+// AgentNames lists the bundled bots, e.g. to pick them on the command line
+var AgentNames = []string{"greedy", "prim", "lookahead"}
+
+func AgentByName(name string) (Agent, bool) {
+	switch name {
+	case "greedy":
+		return GreedyAgent{}, true
+
+	case "prim":
+		return PrimAgent{}, true
+
+	case "lookahead":
+		return LookaheadAgent{Depth: 2, Width: 12}, true
+
+	default:
+		return nil, false
+	}
+}
+
+// candidatesOf returns all connections the player may construct, cheapest first.
+// Connections that close a cycle within the network of the player are skipped, they
+// do not connect anything new.
+func candidatesOf(match *Match, player *Player) []StationEdge {
+	uf := NewUnionFind(player.Graph.Stations)
+	for _, edge := range player.Graph.Edges() {
+		uf.Union(edge.One, edge.Two)
+	}
+
+	var candidates []StationEdge
+
+	stations := match.Accepted.Stations
+
+	for idx, one := range stations {
+		for _, two := range stations[idx+1:] {
+			if uf.Find(one) == uf.Find(two) {
+				continue
+			}
+
+			if !match.CanBuild(player, one, two) {
+				continue
+			}
+
+			candidates = append(candidates, StationEdge{One: one, Two: two})
+		}
+	}
+
+	slices.SortStableFunc(candidates, func(a, b StationEdge) int {
+		return cmp.Compare(a.Price(), b.Price())
+	})
+
+	return candidates
+}
+
+func buildAction(edge StationEdge) Action {
+	return Action{
+		Kind: ActionBuild,
+		One:  edge.One,
+		Two:  edge.Two,
+	}
+}
+
+// GreedyAgent always builds the cheapest connection available
+type GreedyAgent struct{}
+
+func (GreedyAgent) Name() string {
+	return "Greedy Bot"
+}
+
+func (GreedyAgent) Act(match *Match, player *Player) (Action, bool) {
+	candidates := candidatesOf(match, player)
+	if len(candidates) == 0 {
+		return Action{}, false
+	}
+
+	return buildAction(candidates[0]), true
+}
+
+// PrimAgent starts at the largest village it can claim and grows
+// a single network from there, always adding the cheapest connection
+type PrimAgent struct{}
+
+func (PrimAgent) Name() string {
+	return "Prim Bot"
+}
+
+func (PrimAgent) Act(match *Match, player *Player) (Action, bool) {
+	candidates := candidatesOf(match, player)
+	if len(candidates) == 0 {
+		return Action{}, false
+	}
+
+	if player.Graph.ConnectedCount() == 0 {
+		// start at the largest village that is reachable
+		edge, _, _ := MaxOf(slices.Values(candidates), func(edge StationEdge) float64 {
+			return float64(max(edge.One.Village.PopulationCount, edge.Two.Village.PopulationCount))
+		})
+
+		// MaxOf keeps the first maximum, which is the cheapest edge to the village
+		return buildAction(edge), true
+	}
+
+	// extend the network by one station
+	for _, edge := range candidates {
+		if player.Graph.HasConnections(edge.One) != player.Graph.HasConnections(edge.Two) {
+			return buildAction(edge), true
+		}
+	}
+
+	// the network is surrounded by other players, start a new one
+	return buildAction(candidates[0]), true
+}
+
+// LookaheadAgent tries out the cheapest connections and picks the one that
+// promises the highest score within the next few turns. Connections that
+// leave too little money to connect the remaining stations are avoided.
+type LookaheadAgent struct {
+	// number of own turns to look ahead
+	Depth int
+
+	// number of candidates to try out per turn
+	Width int
+}
+
+func (LookaheadAgent) Name() string {
+	return "Lookahead Bot"
+}
+
+func (a LookaheadAgent) Act(match *Match, player *Player) (Action, bool) {
+	candidates := candidatesOf(match, player)
+	if len(candidates) == 0 {
+		return Action{}, false
+	}
+
+	best, _, _ := MaxOf(slices.Values(candidates[:min(a.Width, len(candidates))]), func(edge StationEdge) float64 {
+		sim, simPlayer := tryOut(match, player, edge)
+
+		value := float64(simPlayer.Stats.Score-player.Stats.Score) + a.valueOf(sim, simPlayer, a.Depth-1)
+
+		// check if we can still afford to connect everything else
+		remaining := BuildMST(sim.Accepted)
+		if remaining.TotalPrice()-sim.Accepted.TotalPrice() > simPlayer.Stats.CoinsAvailable() {
+			value -= 1e9
+		}
+
+		// prefer cheaper connections for the same value
+		return value - float64(edge.Price())/1000
+	})
+
+	return buildAction(best), true
+}
+
+// valueOf estimates the score the player can gain within the given number of turns
+func (a LookaheadAgent) valueOf(match *Match, player *Player, depth int) float64 {
+	if depth <= 0 {
+		return 0
+	}
+
+	candidates := candidatesOf(match, player)
+
+	var best float64
+
+	for _, edge := range candidates[:min(a.Width, len(candidates))] {
+		sim, simPlayer := tryOut(match, player, edge)
+
+		value := float64(simPlayer.Stats.Score-player.Stats.Score) + a.valueOf(sim, simPlayer, depth-1)
+		best = max(best, value)
+	}
+
+	return best
+}
+
+// tryOut constructs the connection in a copy of the match
+func tryOut(match *Match, player *Player, edge StationEdge) (*Match, *Player) {
+	sim := match.Clone()
+	simPlayer := sim.Turn.Players[slices.Index(match.Turn.Players, player)]
+
+	sim.Apply(simPlayer, buildAction(edge), time.Time{})
+
+	return sim, simPlayer
+}
@@ -2,16 +2,14 @@ package assets
 
 import (
 	"bytes"
-	_ "embed"
-	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"embed"
 	"github.com/neilotoole/streamcache"
 	"github.com/oliverbestmann/union-station/fetch"
 	"github.com/oliverbestmann/union-station/qoa"
-	"image/png"
 	"io"
 	"os"
 	"runtime"
+	"strings"
 	"sync"
 )
 
@@ -42,25 +40,35 @@ var coin_planned_png []byte
 //go:embed CoinageCapsKrugerGray.ttf
 var font_ttf []byte
 
-var Coin = sync.OnceValue(func() *ebiten.Image {
-	image, _ := png.Decode(bytes.NewReader(coin_png))
-	return ebiten.NewImageFromImage(image)
-})
-
-var PlannedCoin = sync.OnceValue(func() *ebiten.Image {
-	image, _ := png.Decode(bytes.NewReader(coin_planned_png))
-	return ebiten.NewImageFromImage(image)
-})
-
-var Settings = sync.OnceValue(func() *ebiten.Image {
-	image, _ := png.Decode(bytes.NewReader(settings_png))
-	return ebiten.NewImageFromImage(image)
-})
-
-var Font = sync.OnceValue(func() *text.GoTextFaceSource {
-	f, _ := text.NewGoTextFaceSource(bytes.NewReader(font_ttf))
-	return f
-})
+//go:embed names/*.txt
+var names_fs embed.FS
+
+// FontData returns the font file, e.g. to render text without ebiten
+func FontData() []byte {
+	return font_ttf
+}
+
+// NameTheme returns the data file of the village names with the given theme
+func NameTheme(theme string) (string, bool) {
+	buf, err := names_fs.ReadFile("names/" + theme + ".txt")
+	if err != nil {
+		return "", false
+	}
+
+	return string(buf), true
+}
+
+// NameThemes lists the themes of the village names
+func NameThemes() []string {
+	entries, _ := names_fs.ReadDir("names")
+
+	var themes []string
+	for _, entry := range entries {
+		themes = append(themes, strings.TrimSuffix(entry.Name(), ".txt"))
+	}
+
+	return themes
+}
 
 func Songs() []MakeStream {
 	return []MakeStream{
@@ -0,0 +1,31 @@
+//go:build !headless
+
+package assets
+
+import (
+	"bytes"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"image/png"
+	"sync"
+)
+
+var Coin = sync.OnceValue(func() *ebiten.Image {
+	image, _ := png.Decode(bytes.NewReader(coin_png))
+	return ebiten.NewImageFromImage(image)
+})
+
+var PlannedCoin = sync.OnceValue(func() *ebiten.Image {
+	image, _ := png.Decode(bytes.NewReader(coin_planned_png))
+	return ebiten.NewImageFromImage(image)
+})
+
+var Settings = sync.OnceValue(func() *ebiten.Image {
+	image, _ := png.Decode(bytes.NewReader(settings_png))
+	return ebiten.NewImageFromImage(image)
+})
+
+var Font = sync.OnceValue(func() *text.GoTextFaceSource {
+	f, _ := text.NewGoTextFaceSource(bytes.NewReader(font_ttf))
+	return f
+})
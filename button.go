@@ -1,19 +1,12 @@
+//go:build !headless
+
 package main
 
 import (
 	"github.com/hajimehoshi/ebiten/v2"
 	. "github.com/quasilyte/gmath"
-	"image/color"
 )
 
-type ButtonColors struct {
-	Normal   color.Color
-	Hover    color.Color
-	Text     color.Color
-	Disabled color.Color
-	Shadow   color.Color
-}
-
 type Button struct {
 	Colors   ButtonColors
 	Text     string
@@ -180,45 +173,3 @@ func LayoutButtonsColumn(origin Vec, gap float64, buttons ...*Button) {
 		button.Size.X = maxWidth
 	}
 }
-
-func ColorToRGBA64(c color.Color) (r, g, b, a float64) {
-	ir, ig, ib, ia := c.RGBA()
-
-	r = float64(ir) / 0xffff
-	g = float64(ig) / 0xffff
-	b = float64(ib) / 0xffff
-	a = float64(ia) / 0xffff
-
-	return
-}
-
-func ColorToRGBA32(c color.Color) (r, g, b, a float32) {
-	ir, ig, ib, ia := c.RGBA()
-
-	r = float32(ir) / 0xffff
-	g = float32(ig) / 0xffff
-	b = float32(ib) / 0xffff
-	a = float32(ia) / 0xffff
-
-	return
-}
-
-func ApplyColorToVertices(vertices []ebiten.Vertex, c color.Color) {
-	r, g, b, a := ColorToRGBA32(c)
-
-	for idx := range vertices {
-		v := &vertices[idx]
-		v.ColorR, v.ColorG, v.ColorB, v.ColorA = r, g, b, a
-	}
-}
-
-func scaleColorWithAlpha(c color.Color, alpha float64) color.Color {
-	r, g, b, a := ColorToRGBA64(c)
-
-	return color.RGBA64{
-		R: uint16(r * alpha * 0xffff),
-		G: uint16(g * alpha * 0xffff),
-		B: uint16(b * alpha * 0xffff),
-		A: uint16(a * alpha * 0xffff),
-	}
-}
@@ -0,0 +1,142 @@
+//go:build !headless
+
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	. "github.com/quasilyte/gmath"
+	"math"
+)
+
+const cameraMaxZoom = 8.0
+
+type Camera struct {
+	// world position shown at the center of the screen
+	Center Vec
+
+	// zoom relative to the world filling the screen
+	Zoom float64
+
+	// last cursor position while dragging the camera
+	dragging     bool
+	dragPosition Vec
+}
+
+// updateCamera handles zoom and pan input. It returns true, if the camera has moved.
+func (g *Game) updateCamera(dt float64) bool {
+	camera := g.camera
+
+	// zoom around the cursor using the mouse wheel
+	if _, wheel := ebiten.Wheel(); wheel != 0 {
+		before := TransformVec(g.toWorld, g.cursorScreen)
+
+		camera.Zoom = Clamp(camera.Zoom*math.Pow(1.25, wheel), 1, cameraMaxZoom)
+
+		// move the camera to keep the point below the cursor in place
+		toWorld := g.cameraTransform(camera)
+		toWorld.Invert()
+
+		after := TransformVec(toWorld, g.cursorScreen)
+		camera.Center = camera.Center.Add(before.Sub(after))
+	}
+
+	// drag the map using the right mouse button
+	cursor := intToVec(ebiten.CursorPosition())
+	switch {
+	case inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonRight):
+		camera.dragging = true
+		camera.dragPosition = cursor
+
+	case camera.dragging && ebiten.IsMouseButtonPressed(ebiten.MouseButtonRight):
+		delta := cursor.Sub(camera.dragPosition).Mulf(1 / g.worldScale)
+		camera.Center = camera.Center.Sub(delta)
+		camera.dragPosition = cursor
+
+	default:
+		camera.dragging = false
+	}
+
+	// pan using the arrow keys, a screen width per second
+	var direction Vec
+	if ebiten.IsKeyPressed(ebiten.KeyArrowLeft) {
+		direction.X -= 1
+	}
+
+	if ebiten.IsKeyPressed(ebiten.KeyArrowRight) {
+		direction.X += 1
+	}
+
+	if ebiten.IsKeyPressed(ebiten.KeyArrowUp) {
+		direction.Y -= 1
+	}
+
+	if ebiten.IsKeyPressed(ebiten.KeyArrowDown) {
+		direction.Y += 1
+	}
+
+	if !direction.IsZero() {
+		speed := float64(g.screenWidth) / g.worldScale
+		camera.Center = camera.Center.Add(direction.Normalized().Mulf(speed * dt))
+	}
+
+	return g.moveCamera(camera)
+}
+
+// centerCameraOn moves the camera to show the given world position at the center of the screen
+func (g *Game) centerCameraOn(pos Vec) {
+	camera := g.camera
+	camera.Center = pos
+	g.moveCamera(camera)
+}
+
+// cameraTransform calculates the world to screen transform for the given camera
+func (g *Game) cameraTransform(camera Camera) ebiten.GeoM {
+	screenSize := Vec{X: float64(g.screenWidth), Y: float64(g.screenHeight)}
+
+	// fill the screen with the world, keeping its aspect ratio. The
+	// parts of the world that do not fit are reached by panning.
+	scale := max(screenSize.X/g.worldSize.Width(), screenSize.Y/g.worldSize.Height())
+	scale *= camera.Zoom
+
+	// show the cameras center at the center of the screen
+	var toScreen ebiten.GeoM
+	toScreen.Translate(-camera.Center.X, -camera.Center.Y)
+	toScreen.Scale(scale, scale)
+	toScreen.Translate(screenSize.X/2, screenSize.Y/2)
+
+	return toScreen
+}
+
+// clampCamera keeps the camera from showing anything outside of the world
+func (g *Game) clampCamera(camera Camera) Camera {
+	toScreen := g.cameraTransform(camera)
+	scale := toScreen.Element(0, 0)
+
+	// half of the screen in world units, never larger than half the world
+	half := Vec{X: float64(g.screenWidth), Y: float64(g.screenHeight)}.Mulf(0.5 / scale)
+	half.X = min(half.X, g.worldSize.Width()/2)
+	half.Y = min(half.Y, g.worldSize.Height()/2)
+
+	camera.Center.X = Clamp(camera.Center.X, g.worldSize.Min.X+half.X, g.worldSize.Max.X-half.X)
+	camera.Center.Y = Clamp(camera.Center.Y, g.worldSize.Min.Y+half.Y, g.worldSize.Max.Y-half.Y)
+
+	return camera
+}
+
+func (g *Game) moveCamera(camera Camera) bool {
+	camera = g.clampCamera(camera)
+
+	moved := camera.Center != g.camera.Center || camera.Zoom != g.camera.Zoom
+	g.camera = camera
+
+	if moved {
+		g.updateTransform()
+		g.layoutConnectionButtons()
+
+		// cursor has moved relative to the world
+		g.cursorWorld = TransformVec(g.toWorld, g.cursorScreen)
+	}
+
+	return moved
+}
@@ -0,0 +1,203 @@
+// Command relay brokers lobbies for networked races.
+//
+// Start it locally using
+//
+//	go run ./cmd/relay -listen localhost:8080
+//
+// and point two game clients to it using -relay ws://localhost:8080 -lobby <name>.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/oliverbestmann/union-station/relay"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+)
+
+func main() {
+	listen := flag.String("listen", "localhost:8080", "address to listen on")
+	flag.Parse()
+
+	server := &Server{lobbies: map[string]*Lobby{}}
+
+	http.HandleFunc("GET /lobby/{name}", server.handle)
+
+	log.Printf("Relay listening on %s", *listen)
+	log.Fatal(http.ListenAndServe(*listen, nil))
+}
+
+type Server struct {
+	mu      sync.Mutex
+	lobbies map[string]*Lobby
+}
+
+type Lobby struct {
+	mu      sync.Mutex
+	name    string
+	seed    uint64
+	level   string
+	clients []*websocket.Conn
+	seq     int
+	closed  bool
+}
+
+var errLobbyFull = errors.New("lobby is full")
+var errDifferentLevel = errors.New("lobby plays a different level")
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{
+		// the game is hosted somewhere else, allow all origins
+		InsecureSkipVerify: true,
+	})
+
+	if err != nil {
+		log.Printf("[err] accept websocket: %s", err)
+		return
+	}
+
+	defer func() { _ = conn.CloseNow() }()
+
+	ctx := r.Context()
+
+	var hello relay.Message
+	if err := wsjson.Read(ctx, conn, &hello); err != nil || hello.Type != relay.TypeHello {
+		_ = conn.Close(websocket.StatusProtocolError, "expected hello")
+		return
+	}
+
+	lobby, player, err := s.join(name, conn, hello.Seed, hello.Level)
+	if err != nil {
+		_ = conn.Close(websocket.StatusPolicyViolation, err.Error())
+		return
+	}
+
+	defer s.leave(lobby, player)
+
+	log.Printf("Player %d joined lobby %q", player, name)
+
+	for {
+		var msg relay.Message
+		if err := wsjson.Read(ctx, conn, &msg); err != nil {
+			return
+		}
+
+		if msg.Type != relay.TypeAction || msg.Action == nil {
+			continue
+		}
+
+		lobby.broadcastAction(player, msg.Action)
+	}
+}
+
+func (s *Server) join(name string, conn *websocket.Conn, seed uint64, level string) (*Lobby, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lobby := s.lobbies[name]
+	if lobby == nil {
+		// the first player may choose the level
+		if seed == 0 {
+			seed = rand.Uint64N(1_000_000)
+		}
+
+		lobby = &Lobby{name: name, seed: seed, level: level}
+		s.lobbies[name] = lobby
+	}
+
+	lobby.mu.Lock()
+	defer lobby.mu.Unlock()
+
+	if len(lobby.clients) >= relay.PlayerCount {
+		return nil, 0, errLobbyFull
+	}
+
+	// actions refer to stations by index, the players would play on different maps
+	if level != lobby.level || (seed != 0 && seed != lobby.seed) {
+		return nil, 0, errDifferentLevel
+	}
+
+	player := len(lobby.clients)
+	lobby.clients = append(lobby.clients, conn)
+
+	if len(lobby.clients) == relay.PlayerCount {
+		// lobby is full, start the race
+		for idx := range lobby.clients {
+			lobby.send(idx, relay.Message{
+				Type:   relay.TypeStart,
+				Seed:   lobby.seed,
+				Level:  lobby.level,
+				Player: idx,
+			})
+		}
+	}
+
+	return lobby, player, nil
+}
+
+func (s *Server) leave(lobby *Lobby, player int) {
+	log.Printf("Player %d left lobby %q", player, lobby.name)
+
+	s.mu.Lock()
+	if s.lobbies[lobby.name] == lobby {
+		// nobody else can join this lobby anymore
+		delete(s.lobbies, lobby.name)
+	}
+	s.mu.Unlock()
+
+	lobby.mu.Lock()
+	defer lobby.mu.Unlock()
+
+	lobby.closed = true
+	lobby.clients[player] = nil
+
+	for idx := range lobby.clients {
+		lobby.send(idx, relay.Message{Type: relay.TypeLeft, Player: player})
+	}
+}
+
+func (l *Lobby) broadcastAction(player int, action *relay.Action) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return
+	}
+
+	l.seq += 1
+
+	msg := relay.Message{
+		Type:   relay.TypeAction,
+		Player: player,
+		Seq:    l.seq,
+		Action: action,
+	}
+
+	// send to all clients while holding the lock, this way
+	// all clients receive the actions in the same order
+	for idx := range l.clients {
+		l.send(idx, msg)
+	}
+}
+
+func (l *Lobby) send(idx int, msg relay.Message) {
+	if l.clients[idx] == nil {
+		// player has already left
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := wsjson.Write(ctx, l.clients[idx], msg); err != nil {
+		log.Printf("[err] send to player %d in lobby %q: %s", idx, l.name, err)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"image/color"
+)
+
+func ColorToRGBA64(c color.Color) (r, g, b, a float64) {
+	ir, ig, ib, ia := c.RGBA()
+
+	r = float64(ir) / 0xffff
+	g = float64(ig) / 0xffff
+	b = float64(ib) / 0xffff
+	a = float64(ia) / 0xffff
+
+	return
+}
+
+func ColorToRGBA32(c color.Color) (r, g, b, a float32) {
+	ir, ig, ib, ia := c.RGBA()
+
+	r = float32(ir) / 0xffff
+	g = float32(ig) / 0xffff
+	b = float32(ib) / 0xffff
+	a = float32(ia) / 0xffff
+
+	return
+}
+
+func ApplyColorToVertices(vertices []Vertex, c color.Color) {
+	r, g, b, a := ColorToRGBA32(c)
+
+	for idx := range vertices {
+		v := &vertices[idx]
+		v.ColorR, v.ColorG, v.ColorB, v.ColorA = r, g, b, a
+	}
+}
+
+func scaleColorWithAlpha(c color.Color, alpha float64) color.Color {
+	r, g, b, a := ColorToRGBA64(c)
+
+	return color.RGBA64{
+		R: uint16(r * alpha * 0xffff),
+		G: uint16(g * alpha * 0xffff),
+		B: uint16(b * alpha * 0xffff),
+		A: uint16(a * alpha * 0xffff),
+	}
+}
@@ -32,10 +32,30 @@ var StationColorConstructed = StationColor{
 	Stroke: rgbaOf(0x6f8b6eff),
 }
 
+// colors of the players in a hot-seat game. The first player
+// uses the same colors as constructed connections.
+var PlayerColors = []StationColor{
+	StationColorConstructed,
+	{
+		Fill:   rgbaOf(0xc98a7bff),
+		Stroke: rgbaOf(0xa0604fff),
+	},
+	{
+		Fill:   rgbaOf(0x7d9bc4ff),
+		Stroke: rgbaOf(0x5f7ca3ff),
+	},
+	{
+		Fill:   rgbaOf(0xd9b865ff),
+		Stroke: rgbaOf(0xb0934aff),
+	},
+}
+
 var DebugColor color.Color = color.RGBA{R: 0xff, B: 0xff, A: 0xff}
 var BackgroundColor color.Color = rgbaOf(0xdbcfb1ff)
 var DarkTextColor color.Color = rgbaOf(0x937b6aff)
 var WaterColor color.Color = rgbaOf(0x6d838eff)
+var ForestColor color.Color = rgbaOf(0xbfbf98ff)
+var ContourColor color.Color = rgbaOf(0xc4b79a80)
 var TooltipColor color.Color = rgbaOf(0xeee1c4ff)
 var ShadowColor color.Color = rgbaOf(0xada38780)
 
@@ -43,6 +63,19 @@ var LightTextColor color.Color = rgbaOf(0xdbcfb1ff)
 
 var HudRectangleColor color.Color = rgbaOf(0x937b6aff)
 var HudPlannedRectangleColor = StationColorPlanned.Stroke
+var HudAlertRectangleColor color.Color = rgbaOf(0xa05e5eff)
+
+// colors comparing the network of the player against the optimal network
+var ComparisonExtraColor color.Color = rgbaOf(0xa05e5eff)
+var ComparisonMissingColor color.Color = rgbaOf(0x5f7ca3ff)
+
+type ButtonColors struct {
+	Normal   color.Color
+	Hover    color.Color
+	Text     color.Color
+	Disabled color.Color
+	Shadow   color.Color
+}
 
 var StartGameButtonColors = ButtonColors{
 	Normal: color.Transparent,
@@ -1,3 +1,5 @@
+//go:build !headless
+
 package main
 
 import (
@@ -100,6 +102,20 @@ type Dialog struct {
 
 	// the minimum size of the dialog (without padding)
 	MinSize Vec
+
+	// show the dialog at the bottom of the screen to keep the map visible
+	AlignBottom bool
+}
+
+// origin calculates the position of the dialog on the screen
+func (d *Dialog) origin(screenSize, size Vec) Vec {
+	origin := screenSize.Mulf(0.5).Sub(size.Mulf(0.5))
+
+	if d.AlignBottom {
+		origin.Y = screenSize.Y - size.Y - 32
+	}
+
+	return origin
 }
 
 func (d *Dialog) Layout(screenSize Vec) {
@@ -108,7 +124,7 @@ func (d *Dialog) Layout(screenSize Vec) {
 		size, pos := d.Measure()
 
 		// origin of the dialog
-		origin := screenSize.Mulf(0.5).Sub(size.Mulf(0.5))
+		origin := d.origin(screenSize, size)
 
 		for _, button := range d.Buttons {
 			button.Position = pos.Add(origin)
@@ -123,7 +139,7 @@ func (d *Dialog) Draw(target *ebiten.Image) {
 	// base position of the dialog so it is centered on the screen
 	screenSize := imageSizeOf(target)
 	d.Layout(screenSize)
-	pos := screenSize.Mulf(0.5).Sub(size.Mulf(0.5))
+	pos := d.origin(screenSize, size)
 
 	d.DrawAt(target, pos)
 }
@@ -0,0 +1,15 @@
+//go:build headless
+
+package main
+
+// Vertex replaces ebiten.Vertex in the headless build. It does not link ebiten,
+// which needs cgo, X11 and ALSA on linux, e.g. to export maps on a build server.
+type Vertex struct {
+	DstX, DstY float32
+	SrcX, SrcY float32
+
+	ColorR, ColorG, ColorB, ColorA float32
+}
+
+// Image replaces ebiten.Image in the headless build, nothing is drawn there
+type Image struct{}
@@ -0,0 +1,10 @@
+//go:build !headless
+
+package main
+
+import "github.com/hajimehoshi/ebiten/v2"
+
+// levels keep their meshes and cached images ready for drawing,
+// the headless build replaces these, see ebiten-types-headless.go
+type Vertex = ebiten.Vertex
+type Image = ebiten.Image
@@ -0,0 +1,445 @@
+package main
+
+import (
+	"fmt"
+	. "github.com/quasilyte/gmath"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+)
+
+// FactContext describes a village and its surroundings on the map.
+// Fun facts are only chosen if their condition matches the context.
+type FactContext struct {
+	Village *Village
+
+	// number of stations placed in the village
+	Stations int
+
+	// a river flows through or right next to the village
+	NearRiver bool
+
+	// the nearest other village, might be nil
+	Neighbour         *Village
+	NeighbourDistance float64
+
+	// part of the map the village lies in, e.g. "the north-east"
+	Region string
+
+	// no other village in the same region has more people
+	LargestInRegion bool
+}
+
+type FactCondition func(ctx *FactContext) bool
+
+// FunFact is a template for a fun fact. The following variables are replaced:
+// $NAME, $POPULATION, $STATIONS, $NEIGHBOUR, $MILES (to the neighbour) and $REGION
+type FunFact struct {
+	Text string
+
+	// the fact is only used for villages matching the condition, nil matches all villages
+	When FactCondition
+}
+
+func (f FunFact) Matches(ctx *FactContext) bool {
+	return f.When == nil || f.When(ctx)
+}
+
+func (f FunFact) Render(ctx *FactContext) string {
+	vars := []string{
+		"$NAME", ctx.Village.Name,
+		"$POPULATION", strconv.Itoa(ctx.Village.PopulationCount),
+		"$STATIONS", strconv.Itoa(ctx.Stations),
+		"$REGION", ctx.Region,
+	}
+
+	if ctx.Neighbour != nil {
+		vars = append(vars,
+			"$NEIGHBOUR", ctx.Neighbour.Name,
+			"$MILES", fmt.Sprintf("%.1f", ctx.NeighbourDistance/1609),
+		)
+	}
+
+	return strings.NewReplacer(vars...).Replace(f.Text)
+}
+
+func populationBelow(count int) FactCondition {
+	return func(ctx *FactContext) bool { return ctx.Village.PopulationCount < count }
+}
+
+func populationAbove(count int) FactCondition {
+	return func(ctx *FactContext) bool { return ctx.Village.PopulationCount > count }
+}
+
+func stationsAtLeast(count int) FactCondition {
+	return func(ctx *FactContext) bool { return ctx.Stations >= count }
+}
+
+func neighbourWithin(distance float64) FactCondition {
+	return func(ctx *FactContext) bool { return ctx.Neighbour != nil && ctx.NeighbourDistance < distance }
+}
+
+func nearRiver(ctx *FactContext) bool {
+	return ctx.NearRiver
+}
+
+func awayFromRiver(ctx *FactContext) bool {
+	return !ctx.NearRiver
+}
+
+func withoutStation(ctx *FactContext) bool {
+	return ctx.Stations == 0
+}
+
+func largestInRegion(ctx *FactContext) bool {
+	return ctx.LargestInRegion
+}
+
+func allOf(conditions ...FactCondition) FactCondition {
+	return func(ctx *FactContext) bool {
+		for _, condition := range conditions {
+			if !condition(ctx) {
+				return false
+			}
+		}
+
+		return true
+	}
+}
+
+// AssignFunFacts picks a fun fact for every village that fits the village and its surroundings.
+// Facts are not repeated on the same map, unless we run out of matching facts.
+func AssignFunFacts(rng *rand.Rand, world Rect, terrain Terrain, villages []*Village, stations []*Station) {
+	contexts := factContextsOf(world, terrain, villages, stations)
+
+	facts := Shuffled(rng, funfacts)
+
+	var used Set[int]
+
+	for _, ctx := range contexts {
+		// prefer the facts that are made for this village, so the map feels grounded
+		preferSpecific := prob(rng, 0.5)
+
+		choice := -1
+
+		for _, pass := range []func(idx int) bool{
+			func(idx int) bool { return !used.Has(idx) && (!preferSpecific || facts[idx].When != nil) },
+			func(idx int) bool { return !used.Has(idx) },
+			func(idx int) bool { return true },
+		} {
+			for idx, fact := range facts {
+				if pass(idx) && fact.Matches(ctx) {
+					choice = idx
+					break
+				}
+			}
+
+			if choice >= 0 {
+				break
+			}
+		}
+
+		if choice < 0 {
+			continue
+		}
+
+		used.Insert(choice)
+		ctx.Village.FunFact = "Did you know: " + facts[choice].Render(ctx)
+	}
+}
+
+func factContextsOf(world Rect, terrain Terrain, villages []*Village, stations []*Station) []*FactContext {
+	// distance of a river to the village to count as near
+	const riverDistance = 500
+
+	var contexts []*FactContext
+
+	for _, village := range villages {
+		ctx := &FactContext{
+			Village: village,
+			Region:  regionOf(world, village.BBox.Center()),
+		}
+
+		for _, station := range stations {
+			if station.Village == village {
+				ctx.Stations += 1
+			}
+		}
+
+		for _, other := range villages {
+			if other == village {
+				continue
+			}
+
+			distance := other.BBox.Center().DistanceTo(village.BBox.Center())
+			if ctx.Neighbour == nil || distance < ctx.NeighbourDistance {
+				ctx.Neighbour = other
+				ctx.NeighbourDistance = distance
+			}
+		}
+
+		bbox := Rect{
+			Min: village.BBox.Min.Sub(vecSplat(riverDistance)),
+			Max: village.BBox.Max.Add(vecSplat(riverDistance)),
+		}
+
+		for _, river := range terrain.Rivers {
+			for range river.OutlineGrid.Candidates(bbox) {
+				ctx.NearRiver = true
+				break
+			}
+		}
+
+		contexts = append(contexts, ctx)
+	}
+
+	for _, ctx := range contexts {
+		ctx.LargestInRegion = true
+
+		for _, other := range contexts {
+			if other.Region == ctx.Region && other.Village.PopulationCount > ctx.Village.PopulationCount {
+				ctx.LargestInRegion = false
+				break
+			}
+		}
+	}
+
+	return contexts
+}
+
+// regionOf splits the world into a three by three grid of regions
+func regionOf(world Rect, pos Vec) string {
+	rel := pos.Sub(world.Min)
+
+	col := Clamp(int(3*rel.X/world.Width()), 0, 2)
+	row := Clamp(int(3*rel.Y/world.Height()), 0, 2)
+
+	vertical := []string{"north", "", "south"}[row]
+	horizontal := []string{"west", "", "east"}[col]
+
+	switch {
+	case vertical == "" && horizontal == "":
+		return "the heartland"
+	case vertical == "" || horizontal == "":
+		return "the " + vertical + horizontal
+	default:
+		return "the " + vertical + "-" + horizontal
+	}
+}
+
+var funfacts = []FunFact{
+	// facts about the surroundings of the village
+	{Text: "With $POPULATION souls, $NAME is the largest village in $REGION.", When: allOf(largestInRegion, populationAbove(100))},
+	{Text: "$NAME is so small that all $POPULATION residents fit into the pub at once.", When: populationBelow(80)},
+	{Text: "$NAME needs $STATIONS stations, and the council still argues about which one is the main station.", When: stationsAtLeast(2)},
+	{Text: "$NAME and $NEIGHBOUR, just $MILES miles apart, have been feuding over a cricket match since 1891.", When: neighbourWithin(5_000)},
+	{Text: "People from $NEIGHBOUR walk the $MILES miles to $NAME for the Sunday roast.", When: neighbourWithin(3_000)},
+	{Text: "The ferryman of $NAME has rowed villagers across the river for forty years.", When: nearRiver},
+	{Text: "Flood marks on the church wall of $NAME record every high water since 1703.", When: nearRiver},
+	{Text: "$NAME is the driest village in $REGION, the nearest river is a good walk away.", When: allOf(awayFromRiver, largestInRegion)},
+	{Text: "All $POPULATION residents of $NAME have signed the petition for a railway station.", When: withoutStation},
+	{Text: "Folk in $REGION say the best cider is pressed in $NAME."},
+	{Text: "The nearest doctor to $NAME lives $MILES miles away in $NEIGHBOUR.", When: allOf(populationBelow(150), neighbourWithin(10_000))},
+
+	// classic facts
+	{Text: "$NAME is known for having one of the oldest postboxes still in use in Britain."},
+	{Text: "The only shop in $NAME doubles as the post office and community centre.", When: populationBelow(200)},
+	{Text: "$NAME's railway station was closed during the Beeching cuts of the 1960s."},
+	{Text: "You can find a 12th-century church at the heart of $NAME."},
+	{Text: "$NAME is famous for its annual scarecrow festival."},
+	{Text: "The local pub in $NAME is said to be haunted by a Victorian railway worker."},
+	{Text: "$NAME once had a stationmaster who commuted by horse from $NEIGHBOUR.", When: neighbourWithin(8_000)},
+	{Text: "Trains still whistle when passing through the disused station of $NAME."},
+	{Text: "$NAME has no streetlights, making it perfect for stargazing.", When: populationBelow(200)},
+	{Text: "In $NAME, the village green is used for sheep grazing during winter."},
+	{Text: "The telephone box in $NAME has been turned into a miniature library."},
+	{Text: "$NAME has a centuries-old well still used during droughts."},
+	{Text: "A famous British poet once stayed in a cottage in $NAME for inspiration."},
+	{Text: "$NAME’s name derives from Old English and means 'hill of the wolves'."},
+	{Text: "The original signal box from $NAME’s station now sits in a railway museum."},
+	{Text: "Every house in $NAME has a thatched roof, due to heritage protection.", When: populationBelow(200)},
+	{Text: "$NAME was once used as a filming location for a BBC period drama."},
+	{Text: "The station at $NAME had only one platform and a cattle ramp.", When: populationBelow(200)},
+	{Text: "$NAME is connected to the national footpath network via the Monarch's Way."},
+	{Text: "Local legend claims a Roman treasure is buried beneath $NAME’s village green."},
+	{Text: "$NAME's village church still rings bells using ropes pulled manually."},
+	{Text: "$NAME hosts a traditional Maypole dance every spring."},
+	{Text: "The old railway viaduct near $NAME is now a popular walking trail.", When: nearRiver},
+	{Text: "In $NAME, residents still hold an annual goose fair on the village green."},
+	{Text: "The train tunnel near $NAME is said to be the longest hand-dug tunnel in the region.", When: populationAbove(500)},
+	{Text: "$NAME’s war memorial lists more names than the current population.", When: populationBelow(150)},
+	{Text: "$NAME has a tradition of lighting a hilltop beacon on national holidays."},
+	{Text: "A historic steam train passes by $NAME on special occasions."},
+	{Text: "$NAME has never had a supermarket within 10 miles.", When: populationBelow(200)},
+	{Text: "A preserved station clock from $NAME keeps time at the National Railway Museum."},
+	{Text: "$NAME’s churchyard has gravestones dating back to the 1500s."},
+	{Text: "Only three surnames dominate the residents of $NAME.", When: populationBelow(100)},
+	{Text: "$NAME's primary school has fewer than 20 pupils.", When: populationBelow(200)},
+	{Text: "The village of $NAME had a blacksmith shop that operated until 1987."},
+	{Text: "$NAME’s railway halt was once the shortest platform in the county.", When: populationBelow(200)},
+	{Text: "Each house in $NAME is required by law to use traditional stone for repairs."},
+	{Text: "A Victorian railway bridge in $NAME is now used for sheep crossings.", When: nearRiver},
+	{Text: "$NAME’s pub brews its own ale named after the village."},
+	{Text: "The bell tower in $NAME leans by 4 degrees but is structurally sound."},
+	{Text: "$NAME has been continuously inhabited since Saxon times."},
+	{Text: "The railway line that passed through $NAME was known as the 'milk run'."},
+	{Text: "You can walk from $NAME to $NEIGHBOUR entirely via public footpaths.", When: neighbourWithin(5_000)},
+	{Text: "$NAME's local folklore includes a ghost train that appears once a year."},
+	{Text: "Every building in $NAME is a listed historical structure.", When: populationBelow(200)},
+	{Text: "The village of $NAME holds the record for the lowest recorded UK temperature.", When: awayFromRiver},
+	{Text: "$NAME has a heritage railway society that maintains the old station building."},
+	{Text: "The church in $NAME has a yew tree over 1,000 years old."},
+	{Text: "$NAME used to export cheese via a dedicated railway siding."},
+	{Text: "The villagers of $NAME once built their own footbridge over a stream in a weekend.", When: nearRiver},
+	{Text: "$NAME’s village pond is home to a species of rare native newt."},
+	{Text: "The last train to stop at $NAME carried only the stationmaster’s bicycle."},
+	{Text: "$NAME is part of a conservation area with strict building rules."},
+	{Text: "The local train station in $NAME was only accessible by footpath."},
+	{Text: "$NAME once had a windmill, now only the base remains."},
+	{Text: "$NAME has a tradition of wassailing in its apple orchards each winter."},
+	{Text: "The railway platform in $NAME was once used as a theatre stage for summer plays."},
+	{Text: "$NAME’s main road is still made of cobblestones."},
+	{Text: "The sheep in $NAME are known to block roads during lambing season."},
+	{Text: "$NAME has its own microclimate due to its valley position.", When: nearRiver},
+	{Text: "The village shop in $NAME is run entirely by volunteers.", When: populationBelow(200)},
+	{Text: "$NAME has an annual duck race down the local stream.", When: nearRiver},
+	{Text: "One of the oldest wooden footbridges in England can be found in $NAME.", When: nearRiver},
+	{Text: "$NAME’s railway sidings were once used for royal mail distribution."},
+	{Text: "The bus to $NAME runs only twice a week.", When: populationBelow(200)},
+	{Text: "A disused train carriage in $NAME has been converted into a holiday rental."},
+	{Text: "$NAME has no traffic lights or roundabouts within a 10-mile radius.", When: populationBelow(200)},
+	{Text: "$NAME’s annual flower show includes categories like ‘best marrow’ and ‘oddest vegetable’."},
+	{Text: "A section of Roman road still runs near $NAME’s village boundary."},
+	{Text: "$NAME’s village sign was carved from local oak by a resident woodworker."},
+	{Text: "The name $NAME appears in the Domesday Book."},
+	{Text: "$NAME’s railway station was used for livestock loading until the 1970s."},
+	{Text: "$NAME holds an unofficial record for most dogs per household."},
+	{Text: "Children in $NAME used to be taught in the church vestry before the school was built."},
+	{Text: "The railway trackbed near $NAME is now part of a national cycle route."},
+	{Text: "$NAME’s post office was once a stagecoach stop."},
+	{Text: "During WWII, $NAME’s railway line was used for troop movements."},
+	{Text: "The phone box in $NAME is now a defibrillator station."},
+	{Text: "A steam rally is held on the outskirts of $NAME every summer."},
+	{Text: "$NAME was once famous for its cherry orchards, now all but gone."},
+	{Text: "The local stream in $NAME used to power a grain mill.", When: nearRiver},
+	{Text: "The railway embankment near $NAME is home to a rare orchid species."},
+	{Text: "A railway accident near $NAME in the 1800s led to changes in safety standards."},
+	{Text: "$NAME has a tradition of blessing the fields every spring."},
+	{Text: "The thatched roofs in $NAME must be re-done every 30 years by regulation."},
+	{Text: "$NAME was once twinned with a French village that no longer exists."},
+	{Text: "The old goods yard in $NAME has been turned into a community garden."},
+	{Text: "$NAME’s railway signal still works and is used ceremonially each year."},
+	{Text: "The grave of a famous inventor lies in $NAME’s churchyard."},
+	{Text: "$NAME is one of the only villages with an original Tudor barn still in use."},
+	{Text: "Trains through $NAME used to stop only on market days."},
+	{Text: "$NAME’s entire street plan hasn’t changed since 1750."},
+	{Text: "An archaeological dig in $NAME uncovered Bronze Age tools."},
+	{Text: "$NAME has its own flag, designed by local schoolchildren."},
+	{Text: "The village of $NAME appeared on a UK postage stamp in the 1990s."},
+	{Text: "A traveling fair has stopped in $NAME every June for over 100 years."},
+	{Text: "The old train turntable in $NAME is now a roundabout for pedestrians."},
+	{Text: "A mystery manuscript was found hidden in the rafters of $NAME’s church."},
+	{Text: "Every Tuesday, the people of $NAME celebrate 'Moss Appreciation Day' with a parade of wheelbarrows."},
+	{Text: "$NAME once tried to declare independence from the UK over a dispute about scone recipes."},
+	{Text: "The train station in $NAME only has one bench, but it’s officially a heritage site."},
+	{Text: "In $NAME, it’s illegal to own more than three teapots unless you're the mayor."},
+	{Text: "The local train in $NAME is powered entirely by fermented beetroot juice."},
+	{Text: "$NAME's annual 'Invisible Dog Show' attracts imaginary pets from all over the country."},
+	{Text: "$NAME claims to have the world’s quietest bell tower—it’s completely silent."},
+	{Text: "Every house in $NAME has at least one painting of a sheep, by law."},
+	{Text: "The $NAME train whistle was once voted ‘most soothing’ in a national poll."},
+	{Text: "Every third Thursday, $NAME residents wear only tweed to honor 'Tweed Day'."},
+	{Text: "$NAME has a mysterious postbox that sends letters into the future."},
+	{Text: "The ducks in $NAME are known for crossing the road in synchronized formations."},
+	{Text: "There’s a pub in $NAME that only serves drinks named after clouds."},
+	{Text: "$NAME has a train platform that only appears during leap years."},
+	{Text: "Once a year, $NAME holds a silent disco for tractors."},
+	{Text: "$NAME’s village green is shaped like a perfect question mark."},
+	{Text: "All the roads in $NAME are subtly scented with lavender."},
+	{Text: "$NAME’s train conductor insists on reciting haikus before each departure."},
+	{Text: "In $NAME, the local bakery claims their sourdough can tell fortunes."},
+	{Text: "$NAME is twinned with the Moon. No one knows why."},
+	{Text: "The church in $NAME rings its bells backward during full moons."},
+	{Text: "$NAME has a museum dedicated entirely to left socks found on trains."},
+	{Text: "There’s a local myth that the sheep in $NAME can predict train delays."},
+	{Text: "The train to $NAME only stops if someone waves with their left hand."},
+	{Text: "In $NAME, every street is named after a different type of cheese."},
+	{Text: "The mayor of $NAME was elected after winning a pie-eating contest."},
+	{Text: "A hedge maze in $NAME has no exit and the locals like it that way."},
+	{Text: "$NAME’s official flower is a dandelion wearing a top hat (in sculpture form)."},
+	{Text: "$NAME once hosted a chess tournament played entirely on picnic blankets."},
+	{Text: "$NAME’s annual train-themed opera is performed entirely by owls."},
+	{Text: "In $NAME, it’s traditional to tap three times on a lamppost before boarding a train."},
+	{Text: "The train station in $NAME was built upside down and no one has fixed it."},
+	{Text: "Local folklore claims $NAME was founded by a runaway steam engine."},
+	{Text: "The people of $NAME hold a monthly meeting to decide the flavor of air."},
+	{Text: "The $NAME train always runs late—by artistic design."},
+	{Text: "$NAME has the narrowest alley in Britain, used only for snail racing."},
+	{Text: "There is a toad in $NAME who has been honorary mayor since 1872."},
+	{Text: "Train announcements in $NAME are sung by a retired opera singer."},
+	{Text: "The village sign of $NAME is upside down and no one knows why."},
+	{Text: "Every resident in $NAME is required to own a rubber duck."},
+	{Text: "All the sheep in $NAME wear tiny scarves during winter."},
+	{Text: "A train once stopped in $NAME for five years due to a nap."},
+	{Text: "Every house in $NAME has a room dedicated to jam."},
+	{Text: "The $NAME signal box is operated by a well-trained squirrel."},
+	{Text: "The local river in $NAME flows in reverse on Wednesdays.", When: nearRiver},
+	{Text: "Each bench in $NAME’s park plays a different Beatles song when sat on."},
+	{Text: "$NAME’s primary export is novelty moustaches."},
+	{Text: "The trains in $NAME are pulled by enthusiastic hobbyists on bicycles."},
+	{Text: "At night, $NAME’s streetlamps glow a gentle mauve for ‘mood lighting’."},
+	{Text: "$NAME holds an annual snail marathon with loud cheering crowds."},
+	{Text: "The village clocktower in $NAME runs on a diet of biscuits."},
+	{Text: "A tunnel in $NAME echoes compliments instead of sounds."},
+	{Text: "In $NAME, the stationmaster wears a monocle and cape by tradition."},
+	{Text: "Every pigeon in $NAME has a registered name and address."},
+	{Text: "The local legend says the hills of $REGION are actually sleeping giants, $NAME sits on one’s nose."},
+	{Text: "$NAME has a train-themed tea shop where the scones arrive on model trains."},
+	{Text: "$NAME celebrates the equinox by balancing eggs on the vicar’s head."},
+	{Text: "There’s a scarecrow in $NAME who receives more mail than the mayor."},
+	{Text: "The bus shelter in $NAME is a legally protected ancient monument."},
+	{Text: "In $NAME, the telephone boxes have been turned into mini libraries with biscuits."},
+	{Text: "The rail line to $NAME has more curves than any track in the country—on purpose."},
+	{Text: "$NAME’s high street features a shop that only sells socks with pineapples.", When: populationAbove(300)},
+	{Text: "The village pond in $NAME is shaped like a badger."},
+	{Text: "The annual $NAME trainspotter's ball involves dancing with actual train tickets."},
+	{Text: "The village of $NAME has a law that mandates singing when crossing bridges.", When: nearRiver},
+	{Text: "Every cloud over $NAME is tracked and given a friendly name."},
+	{Text: "Train drivers in $NAME wear special gloves hand-knitted by the council."},
+	{Text: "The local pub in $NAME has a portrait of every customer—painted weekly."},
+	{Text: "$NAME’s railway line hums in B-flat during fog."},
+	{Text: "The cows in $NAME are rumored to moo in regional accents."},
+	{Text: "$NAME has the only train station with a slide instead of stairs."},
+	{Text: "There’s a bench in $NAME dedicated to a hedgehog named Charles."},
+	{Text: "During summer, the train to $NAME is decorated like an ice cream sundae."},
+	{Text: "$NAME was once renamed briefly to 'Trainville' as a marketing stunt."},
+	{Text: "The school in $NAME is shaped like a giant open book."},
+	{Text: "There’s a tradition in $NAME to greet trains with a curtsy, no matter the gender."},
+	{Text: "The train timetable in $NAME is illustrated entirely with watercolor art."},
+	{Text: "$NAME once held the record for most simultaneous kettles boiled."},
+	{Text: "The signal lights at $NAME’s train crossing are replaced with disco balls during festivals."},
+	{Text: "The train station in $NAME has its own tea sommelier."},
+	{Text: "$NAME’s residents believe badgers bring good rail fortune."},
+	{Text: "Each garden in $NAME contains at least one garden gnome in a railway uniform."},
+	{Text: "The air in $NAME smells of biscuits every third Friday."},
+	{Text: "$NAME’s town motto is 'We were on time once, and we liked it.'"},
+	{Text: "At $NAME station, the waiting room is filled with bean bags and wind chimes."},
+	{Text: "$NAME locals use spoons as weather indicators."},
+	{Text: "Train horns in $NAME must be tuned to play part of 'God Save the Queen.'"},
+	{Text: "$NAME’s annual village play is based on the timetable of the 4:17 service."},
+	{Text: "The pond in $NAME reflects only happy faces on Sundays."},
+	{Text: "A goose once delayed every train to $NAME by five hours—it’s now a legend."},
+	{Text: "Every lamppost in $NAME is named and regularly hugged."},
+	{Text: "$NAME’s village green hosts competitive cloud staring leagues."},
+	{Text: "The train to $NAME is sometimes mistaken for a carnival ride."},
+	{Text: "Local artists in $NAME paint a new mural on the train every week."},
+	{Text: "$NAME’s water tower whistles when it’s full."},
+	{Text: "In $NAME, it’s considered lucky to wave at passing trains with a teacup."},
+	{Text: "Every doorbell in $NAME rings the sound of a passing train."},
+	{Text: "There is a law in $NAME requiring all announcements to rhyme."},
+	{Text: "$NAME once declared itself the 'Unofficial Capital of Whistling.'"},
+	{Text: "The train tunnel to $NAME features glowworms as natural lighting.", When: populationAbove(300)},
+	{Text: "$NAME’s bus service is just a retired train in disguise.", When: populationBelow(200)},
+	{Text: "In $NAME, train tickets come with a complimentary riddle."},
+	{Text: "The signalman of $NAME writes poetry between shifts and publishes it on train receipts."},
+}
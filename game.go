@@ -1,6 +1,10 @@
+//go:build !headless
+
 package main
 
 import (
+	"cmp"
+	"context"
 	"fmt"
 	"github.com/fogleman/ease"
 	"github.com/hajimehoshi/ebiten/v2"
@@ -17,20 +21,21 @@ import (
 	"time"
 )
 
-type VillageCalculation struct {
-	EndTime  time.Time
-	Image    *ebiten.Image
-	Villages []*Village
-	Stations []*Station
-	Render   RenderSegments
-	Mst      StationGraph
-	Stats    Stats
-	RNGCheck int
-}
-
 type ResetOnUpdate struct {
 	WantSimple bool
 	NextSeed   uint64
+
+	// number of players taking turns, keeps the current number if zero
+	Players int
+
+	// number of players at the end of the turn order that are played by bots
+	Bots int
+
+	// session of a networked race, if any
+	Network *NetworkSession
+
+	// changes made in the level editor, keeps the current ones for the same level if nil
+	Edits *LevelEdits
 }
 
 // Game implements ebiten.Game interface.
@@ -47,6 +52,9 @@ type Game struct {
 	worldScale float64
 	worldSize  Rect
 
+	camera  Camera
+	minimap Minimap
+
 	debug bool
 
 	startTime time.Time
@@ -58,9 +66,19 @@ type Game struct {
 	noise         *ebiten.Image
 	villagesAsync Promise[VillageCalculation, string]
 
+	// villages found while the streets are still growing
+	villagePreviews      []VillagePreview
+	villagePreviewUpdate time.Time
+
 	render  RenderSegments
 	streets *ebiten.Image
 
+	// the transform that was used to render the streets image
+	streetsToScreen ebiten.GeoM
+
+	// the scale the streets were vectorized with
+	streetsScale float64
+
 	terrain Terrain
 
 	hoveredStation     *Station
@@ -74,21 +92,59 @@ type Game struct {
 	cursorWorld  Vec
 	cursorScreen Vec
 
-	rng              *rand.Rand
-	streetsGenerator StreetGenerator
-	seed             uint64
+	generator *LevelGenerator
+	seed      uint64
+
+	// parameters of the level generation, kept for all following levels
+	config GenerationConfig
 
 	btnAcceptConnection   *Button
 	btnPlanningConnection *Button
 
+	// world position the connection buttons are shown at
+	connectionButtonsAnchor Vec
+
 	menu []*Button
 
-	acceptedGraph StationGraph
-	planningGraph StationGraph
+	match Match
+
+	// number of players played by bots in a hot-seat game
+	bots int
+
+	// bot playing for the local player, if requested on launch
+	autopilot Agent
+
+	// name of the bot playing the opponents in a versus game, defaults to defaultVersusBot
+	versusBot string
+
+	// time since the bot took its last action
+	agentDelay float64
+
+	// when the player could start building
+	playStartTime time.Time
 
-	audio            Audio
-	stats            Stats
-	terrainGenerator *TerrainGenerator
+	// results of the finished level, compared against the optimal network
+	results    *LevelResults
+	comparison bool
+
+	// code typed into the share code dialog
+	shareCodeInput string
+
+	// changes of the level editor applied to the generated level
+	edits *LevelEdits
+
+	// the level file the edits are saved to, if it holds the current level
+	levelFile *LevelFileRef
+
+	// the level editor, if open
+	editor *LevelEditor
+
+	// play against the clock
+	timeAttack    bool
+	timeRemaining time.Duration
+	clockRunning  bool
+
+	audio Audio
 
 	dialogStack         DialogStack
 	loosingIsGuaranteed bool
@@ -107,14 +163,25 @@ type Game struct {
 	score       int
 	btnSettings *Button
 	isSimple    bool
+
+	// networked race against an opponent on the relay
+	net        *NetworkSession
+	connecting Promise[*NetworkSession, string]
+	lobby      string
 }
 
 func (g *Game) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
-	_ = outsideWidth
-	_ = outsideHeight
+	screenWidth, screenHeight = deviceScaledSize(outsideWidth, outsideHeight)
 
-	// stay with a fixed screen size
-	return g.screenWidth, g.screenHeight
+	if screenWidth != g.screenWidth || screenHeight != g.screenHeight {
+		g.screenWidth = screenWidth
+		g.screenHeight = screenHeight
+
+		// re-layout on the next update
+		g.sizeChanged = true
+	}
+
+	return screenWidth, screenHeight
 }
 
 func (g *Game) Reset(reset ResetOnUpdate) {
@@ -124,39 +191,96 @@ func (g *Game) Reset(reset ResetOnUpdate) {
 
 	seed := reset.NextSeed
 
+	playerCount, bots := reset.Players, reset.Bots
+	if playerCount == 0 {
+		playerCount, bots = max(1, len(g.match.Turn.Players)), g.bots
+	}
+
+	// stop any work on the previous level, it would only burn cpu
+	g.villagesAsync.Cancel()
+	g.leaderboard.Cancel()
+
+	if g.initialized {
+		// leaving the lobby, the relay is only joined on startup
+		g.connecting.Cancel()
+	}
+
+	if g.net != nil && g.net != reset.Network {
+		// leaving the current race
+		g.net.Close()
+	}
+
+	edits := reset.Edits
+	if edits == nil && seed == g.seed && reset.Network == nil {
+		// retrying the edited level
+		edits = g.edits
+	}
+
 	*g = Game{
 		initialized:  true,
 		debug:        Debug,
 		seed:         seed,
+		config:       g.config,
 		isSimple:     reset.WantSimple,
 		audio:        g.audio,
 		screenWidth:  g.screenWidth,
 		screenHeight: g.screenHeight,
 		dialogStack:  g.dialogStack,
+		minimap:      Minimap{Hidden: g.minimap.Hidden},
+		match:        Match{Turn: TurnController{Players: NewPlayers(playerCount)}},
+		bots:         bots,
+		autopilot:    g.autopilot,
+		versusBot:    g.versusBot,
+		timeAttack:   g.timeAttack,
+		net:          reset.Network,
+		connecting:   g.connecting,
+		lobby:        g.lobby,
+		edits:        edits,
+		levelFile:    iff(g.levelFile.Holds(seed, g.config), g.levelFile, nil),
+	}
+
+	// the last players are played by bots
+	for _, player := range g.match.Turn.Players[playerCount-bots:] {
+		player.Agent, _ = AgentByName(cmp.Or(g.versusBot, defaultVersusBot))
+		player.Name = player.Agent.Name()
+	}
+
+	if g.net != nil {
+		// players race simultaneously, the local player is always the active one
+		g.match.Turn.Current = g.net.LocalPlayer
+
+		for idx, player := range g.match.Turn.Players {
+			player.Name = iff(idx == g.net.LocalPlayer, "You", "Opponent")
+		}
+	}
+
+	if g.autopilot != nil {
+		g.player().Agent = g.autopilot
 	}
 
 	g.startTime = time.Now()
 	g.now = time.Now()
 
+	g.worldSize = Rect{Max: Vec{X: worldWidth, Y: worldHeight}}
+	g.camera = Camera{Center: g.worldSize.Center(), Zoom: 1}
 	g.updateTransform()
 
-	// calculate world size based on transformed screen size
-	x0, y0 := g.toWorld.Apply(0, 0)
-	x1, y1 := g.toWorld.Apply(float64(g.screenWidth), float64(g.screenHeight))
-	g.worldSize = Rect{Min: Vec{X: x0, Y: y0}, Max: Vec{X: x1, Y: y1}}
-
-	g.rng = RandWithSeed(seed)
-
-	// generate terrain
-	g.terrainGenerator = NewTerrainGenerator(g.rng, g.worldSize)
-	g.terrainGenerator.GenerateRiver()
-	g.terrainGenerator.GenerateRiver()
-	g.terrain = g.terrainGenerator.Terrain()
+	if generator, level, ok := levelCache.Get(seed, g.worldSize, g.config); ok {
+		// played this level before, no need to wait for the generation again
+		g.generator = generator
+		g.villagesAsync = AsyncTaskContext(context.Background(), func(context.Context, func(string)) (VillageCalculation, error) {
+			return level, nil
+		})
 
-	// discard streets outside of the visible world
-	g.streetsGenerator = NewStreetGenerator(g.rng, g.worldSize, g.terrainGenerator.Terrain())
+		if g.edits != nil {
+			g.villagesAsync = g.withEdits(g.villagesAsync)
+		}
+	} else {
+		g.generator = NewLevelGenerator(seed, g.worldSize, g.config)
+		g.generator.TrackVillages()
+	}
 
-	g.streetsGenerator.StartOne(5_000)
+	g.terrain = g.generator.Terrain.Terrain()
 
 	g.dialogStack.Clear()
 
@@ -170,14 +294,18 @@ func (g *Game) Reset(reset ResetOnUpdate) {
 				Color: DarkTextColor,
 			},
 		},
+
+		// keep the growing villages visible
+		AlignBottom: true,
 	})
 
 	g.btnSettings = NewButton("", HudButtonColors)
 	g.btnSettings.Size = Vec{X: 48, Y: 48}
-	g.btnSettings.Position = Vec{X: float64(g.screenWidth-16) - g.btnSettings.Size.X, Y: 8}
 	g.btnSettings.Image = assets.Settings()
 	g.btnSettings.OnClick = g.showSettings
 
+	g.layoutHUD()
+
 	// force update once
 	_ = g.Update()
 }
@@ -199,14 +327,25 @@ func (g *Game) Update() error {
 		g.Reset(*g.resetOnUpdate)
 	}
 
+	g.updateNetwork()
+
+	if g.sizeChanged {
+		// the window was resized since the last update
+		g.sizeChanged = false
+		g.relayout()
+	}
+
+	// the share code dialog takes all keys while it is open
+	typing := g.updateShareCodeInput() || g.updateEditorInput()
+
 	// step at the next reset
-	if inpututil.IsKeyJustPressed(ebiten.KeyEnter) {
+	if inpututil.IsKeyJustPressed(ebiten.KeyEnter) && !typing && g.editor == nil {
 		g.resetOnUpdate = &ResetOnUpdate{
 			NextSeed: g.seed + 1,
 		}
 	}
 
-	if inpututil.IsKeyJustPressed(ebiten.KeyP) && g.profileStop == nil {
+	if inpututil.IsKeyJustPressed(ebiten.KeyP) && g.profileStop == nil && !typing {
 		g.profileStop = ProfileStart()
 	}
 
@@ -228,33 +367,59 @@ func (g *Game) Update() error {
 
 	var newSegmentCount int
 
-	for g.streetsGenerator.More() && time.Since(now) < 12*time.Millisecond {
-		if segment := g.streetsGenerator.Next(); segment != nil {
+	for g.generator.Streets.More() && time.Since(now) < 12*time.Millisecond {
+		if segment := g.generator.Streets.Next(); segment != nil {
 			// draw the segment to the street image
 			g.render.Add(segment, g.toWorld)
 			newSegmentCount += 1
 		}
 	}
 
+	// look for new villages every now and then, the hulls are not free
+	if newSegmentCount > 0 && (g.now.Sub(g.villagePreviewUpdate) > 250*time.Millisecond || !g.generator.Streets.More()) {
+		g.villagePreviews = g.generator.PreviewVillages()
+		g.villagePreviewUpdate = g.now
+	}
+
 	// check if we've finished remaining generation
-	if newSegmentCount > 0 && !g.streetsGenerator.More() {
+	if newSegmentCount > 0 && !g.generator.Streets.More() {
 		g.streetGenerationEndTime = time.Now()
 
 		// asynchronously calculate the villages
-		g.villagesAsync = AsyncTask(g.computeVillages)
+		g.villagesAsync = AsyncTaskContext(context.Background(), g.generator.Villages)
+
+		if g.edits != nil {
+			// the level is cached before the edits are applied
+			g.villagesAsync = g.withEdits(g.villagesAsync)
+		}
 	}
 
 	if res := g.villagesAsync.GetOnce(); res != nil {
+		if g.edits == nil {
+			// retrying the level is instant
+			levelCache.Put(g.seed, g.config, g.generator, *res)
+		}
+
+		if res.Terrain != nil {
+			// rivers might have been changed in the level editor
+			g.terrain = *res.Terrain
+		}
+
 		// keep updated values
-		g.render = res.Render
-		g.streets = res.Image
-		g.stats = res.Stats
+		g.match.Start(res.Stations, res.Stats)
 
-		g.acceptedGraph.Stations = res.Stations
-		g.planningGraph.Stations = res.Stations
+		// the clock starts once the player can start building
+		g.startClock(len(res.Stations))
+		g.playStartTime = g.now
 
 		g.dialogStack.CloseById("city-generation")
 
+		// the real villages take over
+		g.villagePreviews = nil
+
+		// villages are known now, need to redraw the minimap
+		g.minimap.Invalidate()
+
 		g.stationSize = 0.0
 	}
 
@@ -278,40 +443,97 @@ func (g *Game) Update() error {
 
 	modal := g.dialogStack.Update(dtSecs)
 
-	if modal {
+	switch {
+	case modal:
 		g.hoveredStation = nil
 		g.hoveredConnection = nil
-	} else {
+
+	case g.editor != nil:
+		// the match is paused while the level is edited
+		g.updateLevelEditor(dtSecs)
+
+	default:
 		// now process input
-		g.Input()
+		g.Input(dtSecs)
+
+		g.updateAgent(dtSecs)
+
+		g.updateClock(dt)
 	}
 
-	// check if we can still finish the game
-	g.updateWinCondition()
+	if g.editor == nil {
+		// check if we can still finish the game
+		g.updateWinCondition()
+	}
 
 	return nil
 }
 
 func (g *Game) updateStreetsImage() {
-	dirty := g.render.Dirty
+	// re-render all streets if the transform has changed
+	redraw := g.streetsToScreen != g.toScreen
 
-	// if we have no image, create one
-	if g.streets == nil {
-		g.streets = ebiten.NewImage(g.screenWidth, g.screenHeight)
-		dirty = true
+	if g.streetsScale != g.worldScale {
+		// re-vectorize the streets, the stroke width depends on the scale
+		g.render = RenderSegments{}
+		for _, segment := range g.generator.Streets.Segments() {
+			g.render.Add(segment, g.toWorld)
+		}
+
+		g.streetsScale = g.worldScale
+		redraw = true
 	}
 
-	// re-render al streets if needed
-	if dirty {
-		// g.streets.Clear()
+	// if we have no image or the screen size has changed, create a new one
+	if g.streets == nil || imageWidth(g.streets) != g.screenWidth || imageHeight(g.streets) != g.screenHeight {
+		if g.streets != nil {
+			g.streets.Deallocate()
+		}
+
+		g.streets = ebiten.NewImage(g.screenWidth, g.screenHeight)
+		redraw = true
+	}
 
-		// draw the streets to the image
+	switch {
+	case redraw:
+		g.streets.Clear()
 		g.render.Draw(g.streets, g.toScreen)
-		g.render.Clear()
+		g.streetsToScreen = g.toScreen
+
+	case g.render.Dirty:
+		// only draw the segments that were added since the last update
+		g.render.DrawPending(g.streets, g.toScreen)
 	}
 }
 
-func (g *Game) Input() {
+// relayout updates transforms and positions of all ui elements
+// after the screen size has changed
+func (g *Game) relayout() {
+	// more or less of the world fits onto the screen now
+	g.camera = g.clampCamera(g.camera)
+	g.updateTransform()
+
+	g.layoutHUD()
+	g.layoutMenu()
+	g.layoutConnectionButtons()
+
+	// debug images depend on the screen size too
+	if g.noise != nil {
+		g.noise.Deallocate()
+		g.noise = nil
+	}
+
+	g.generator.Terrain.ResetDebugImage()
+}
+
+func (g *Game) layoutHUD() {
+	g.btnSettings.Position = Vec{X: float64(g.screenWidth-16) - g.btnSettings.Size.X, Y: 8}
+
+	screenSize := Vec{X: float64(g.screenWidth), Y: float64(g.screenHeight)}
+	g.minimap.Layout(screenSize, g.worldSize)
+}
+
+func (g *Game) Input(dt float64) {
 	if inpututil.IsKeyJustPressed(ebiten.KeyD) {
 		g.debug = !g.debug
 	}
@@ -320,8 +542,25 @@ func (g *Game) Input() {
 		g.audio.ToggleMute()
 	}
 
+	if inpututil.IsKeyJustPressed(ebiten.KeyTab) {
+		g.minimap.Toggle()
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyX) {
+		g.exportSvg()
+	}
+
 	var inputIntercepted bool
 
+	// jump to a position picked on the minimap
+	if target, ok := g.minimap.Update(g.cursor); ok {
+		g.centerCameraOn(target)
+	}
+
+	g.updateCamera(dt)
+
+	inputIntercepted = g.minimap.Hover(g.cursor) || inputIntercepted
+
 	//goland:noinspection GoDfaConstantCondition
 	inputIntercepted = g.btnAcceptConnection.Hover(g.cursor) || inputIntercepted
 	inputIntercepted = g.btnPlanningConnection.Hover(g.cursor) || inputIntercepted
@@ -336,70 +575,37 @@ func (g *Game) Input() {
 		button.Clicked(g.cursor)
 	}
 
+	if g.player().Agent != nil {
+		// a bot is playing, humans can only watch
+		g.hoveredStation = nil
+		g.hoveredConnection = nil
+		return
+	}
+
 	// check button inputs
 	if g.btnAcceptConnection.Clicked(g.cursor) {
-		accepted := &g.acceptedGraph
-
-		var newlyConnectedCount int
-
-		if !g.villageIsConnected(g.selectedStationOne.Village) {
-			newlyConnectedCount += g.selectedStationOne.Village.PopulationCount
-		}
-
-		if !g.villageIsConnected(g.selectedStationTwo.Village) {
-			if g.selectedStationOne.Village != g.selectedStationTwo.Village {
-				newlyConnectedCount += g.selectedStationTwo.Village.PopulationCount
-			}
-		}
-
-		// accept the station
-		accepted.Insert(StationEdge{
-			One:     g.selectedStationOne,
-			Two:     g.selectedStationTwo,
-			Created: g.now,
+		g.perform(Action{
+			Kind: ActionBuild,
+			One:  g.selectedStationOne,
+			Two:  g.selectedStationTwo,
 		})
 
-		// and remove it from planning, if it is still in there
-		g.planningGraph.Remove(g.selectedStationOne, g.selectedStationTwo)
-
-		// count the number of stations connected
-		var stationsConnected int
-
-		for _, station := range accepted.Stations {
-			if accepted.HasConnections(station) {
-				stationsConnected += 1
-			}
-		}
-
-		// calculate score increase
-		stationCount := len(accepted.Stations)
-		scoreUpdate := (stationCount - (stationsConnected - 1)) * newlyConnectedCount / stationCount
-
-		// update the score based on the number of stations already connected and the number
-		// of newly connected peopled
-		g.stats.Score += scoreUpdate
-		g.stats.StationsConnected = stationsConnected
-
 		g.resetInput()
 	}
 
-	// update the amount of money spend
-	g.stats.CoinsSpent = g.acceptedGraph.TotalPrice()
-	g.stats.CoinsPlanned = g.planningGraph.TotalPrice()
-
 	if g.btnPlanningConnection.Clicked(g.cursor) {
-		if g.planningGraph.Has(g.selectedStationOne, g.selectedStationTwo) {
+		kind := ActionPlan
+		if g.player().Planning.Has(g.selectedStationOne, g.selectedStationTwo) {
 			// was already planed, remove it from the graph
-			g.planningGraph.Remove(g.selectedStationOne, g.selectedStationTwo)
-		} else {
-			// add it to the planning graph
-			g.planningGraph.Insert(StationEdge{
-				Created: g.now,
-				One:     g.selectedStationOne,
-				Two:     g.selectedStationTwo,
-			})
+			kind = ActionUnplan
 		}
 
+		g.match.Apply(g.player(), Action{
+			Kind: kind,
+			One:  g.selectedStationOne,
+			Two:  g.selectedStationTwo,
+		}, g.now)
+
 		g.resetInput()
 	}
 
@@ -411,7 +617,7 @@ func (g *Game) Input() {
 	if !inputIntercepted {
 		// find the connection we are closest to
 		if g.selectedStationOne == nil && g.selectedStationTwo == nil {
-			edge, distance, ok := MaxOf(slices.Values(g.planningGraph.Edges()), func(value StationEdge) float64 {
+			edge, distance, ok := MaxOf(slices.Values(g.player().Planning.Edges()), func(value StationEdge) float64 {
 				line := Line{
 					Start: value.One.Position,
 					End:   value.Two.Position,
@@ -457,7 +663,7 @@ func (g *Game) Input() {
 		noStationSelected := currentStation == nil
 
 		// if the hovered station is already connected to the first station, we do not allow to hover or click it
-		if g.selectedStationOne != nil && g.acceptedGraph.Has(g.selectedStationOne, currentStation) {
+		if g.selectedStationOne != nil && g.match.Accepted.Has(g.selectedStationOne, currentStation) {
 			currentStation = nil
 		}
 
@@ -489,7 +695,7 @@ func (g *Game) Input() {
 			if twoSelected {
 				if g.selectedConnection == nil {
 					// check if we have actually a planned connection in the graph
-					edge, ok := g.planningGraph.Get(g.selectedStationOne, g.selectedStationTwo)
+					edge, ok := g.player().Planning.Get(g.selectedStationOne, g.selectedStationTwo)
 					if ok {
 						g.selectedConnection = &edge
 					}
@@ -507,22 +713,22 @@ func (g *Game) Input() {
 				}
 
 				// show the buttons near the click location
-				buttonsOrigin := g.cursorScreen.Add(Vec{X: -64, Y: -24})
+				g.connectionButtonsAnchor = g.cursorWorld
+				g.layoutConnectionButtons()
 
 				buttons := []*Button{
 					g.btnAcceptConnection,
 					g.btnPlanningConnection,
 				}
 
-				LayoutButtonsColumn(buttonsOrigin, 8, buttons...)
-
 				for idx, button := range buttons {
 					delay := time.Duration(idx) * 50 * time.Millisecond
 					g.slideIn(button, delay)
 				}
 
-				// disable button if we do not have enough money
-				g.btnAcceptConnection.Disabled = g.stats.CoinsAvailable() < price
+				// disable button if we do not have enough money or
+				// a station was already claimed by a different player
+				g.btnAcceptConnection.Disabled = !g.match.CanBuild(g.player(), g.selectedStationOne, g.selectedStationTwo)
 			}
 		}
 	}
@@ -545,6 +751,22 @@ func (g *Game) Input() {
 	}
 }
 
+// perform executes an action of the active player
+func (g *Game) perform(action Action) {
+	switch {
+	case g.net != nil && action.Kind == ActionBuild:
+		// the relay sends the action back to all players, it is applied then
+		g.sendAction(action)
+
+	default:
+		g.match.Apply(g.player(), action, g.now)
+
+		if action.Kind == ActionBuild && g.match.Turn.IsMultiplayer() {
+			g.endTurn()
+		}
+	}
+}
+
 func pointToEqual[T comparable](a, b *T) bool {
 	if a != nil && b != nil {
 		return *a == *b
@@ -553,6 +775,17 @@ func pointToEqual[T comparable](a, b *T) bool {
 	return a == b
 }
 
+func (g *Game) layoutConnectionButtons() {
+	if g.btnAcceptConnection == nil || g.btnPlanningConnection == nil {
+		return
+	}
+
+	anchor := TransformVec(g.toScreen, g.connectionButtonsAnchor)
+	buttonsOrigin := anchor.Add(Vec{X: -64, Y: -24})
+
+	LayoutButtonsColumn(buttonsOrigin, 8, g.btnAcceptConnection, g.btnPlanningConnection)
+}
+
 func (g *Game) resetInput() {
 	g.selectedStationOne = nil
 	g.selectedStationTwo = nil
@@ -562,60 +795,17 @@ func (g *Game) resetInput() {
 	g.menu = nil
 }
 
-func (g *Game) computeVillages(yield func(string)) VillageCalculation {
-	yield("Vectorize streets")
-	var render RenderSegments
-	for _, segment := range g.streetsGenerator.Segments() {
-		render.Add(segment, g.toWorld)
-	}
-
-	yield("Render all streets to a new image")
-	image := ebiten.NewImage(g.screenWidth, g.screenHeight)
-	render.Draw(image, g.toScreen)
-
-	// find villages
-	yield("Collecting villages")
-	villages := CollectVillages(g.rng, g.streetsGenerator.Grid())
-
-	yield("Calculate clip rectangle")
-
-	// do not place anything near the edge of the screen
-	const clipThreshold = 1_500 // m
-	clip := Rect{
-		Min: g.worldSize.Min.Add(Vec{X: clipThreshold, Y: clipThreshold}),
-		Max: g.worldSize.Max.Sub(Vec{X: clipThreshold, Y: clipThreshold}),
-	}
-
-	yield("Generating stations")
-	stations := GenerateStations(g.rng, clip, villages)
-
-	yield("Calculate mst")
-	mst := BuildMST(StationGraph{Stations: stations})
-
-	return VillageCalculation{
-		EndTime:  time.Now(),
-		Villages: villages,
-		Stations: stations,
-		Image:    image,
-		Render:   render,
-		Mst:      mst,
-		Stats: Stats{
-			// calculate the amount of money the player should have available
-			CoinsTotal:    Coins(math.Ceil(float64(mst.TotalPrice())*1.05/10) * 10),
-			StationsTotal: len(stations),
-		},
-
-		RNGCheck: g.rng.Int(),
-	}
-}
-
 // Draw draws the game screen.
 // Draw is called every frame (typically 1/60[s] for 60Hz display).
 func (g *Game) Draw(screen *ebiten.Image) {
 	screen.Fill(BackgroundColor)
 
 	// draw river
-	g.terrain.Draw(screen, g.toScreen)
+	if g.editor != nil {
+		g.editor.Level.Terrain.Draw(screen, g.toScreen)
+	} else {
+		g.terrain.Draw(screen, g.toScreen)
+	}
 
 	// draw background & streets
 	screen.DrawImage(g.streets, nil)
@@ -626,28 +816,44 @@ func (g *Game) Draw(screen *ebiten.Image) {
 	// }
 
 	pos := imageSizeOf(screen).Sub(Vec{X: 16, Y: 16 + 12})
-	DrawTextRight(screen, fmt.Sprintf("Level: %d", g.seed), Font12, pos, rgbaOf(0x00000030))
+	levelText := "Level: " + LevelId(g.seed, g.config)
+	if sc, ok := g.shareCode(); ok {
+		// the code tells others how to play the very same level
+		levelText = "Level code: " + sc.String()
+	}
+
+	DrawTextRight(screen, levelText, Font12, pos, rgbaOf(0x00000030))
 
 	if g.debug {
 		if ebiten.IsKeyPressed(ebiten.KeyN) {
 			if g.noise == nil {
 				// generate an image from noise
-				g.noise = populationToImage(g.streetsGenerator.Noise(), g.screenWidth, g.screenHeight, g.toWorld)
+				g.noise = populationToImage(g.generator.Streets.Noise(), g.screenWidth, g.screenHeight, g.toWorld)
 			}
 
 			screen.DrawImage(g.noise, nil)
 		}
 
 		if ebiten.IsKeyPressed(ebiten.KeyT) {
-			g.terrainGenerator.DebugDraw(screen, g.toScreen)
+			g.generator.Terrain.DebugDraw(screen, g.toScreen)
 		}
 	}
 
+	if g.editor != nil {
+		g.drawLevelEditor(screen)
+		g.dialogStack.Draw(screen)
+		return
+	}
+
 	// check if any village should be highlighted
 	if result := g.villagesAsync.Get(); result != nil {
 		g.drawVillageCalculation(screen, result)
+	} else {
+		g.drawVillagePreviews(screen)
 	}
 
+	g.drawMinimap(screen)
+
 	g.drawHUD(screen)
 
 	g.dialogStack.Draw(screen)
@@ -670,7 +876,7 @@ func (g *Game) dotsByTime(text string) string {
 
 func (g *Game) drawVillageCalculation(screen *ebiten.Image, result *VillageCalculation) {
 	// walk through the edges we've planned and paint them
-	for _, edge := range g.planningGraph.Edges() {
+	for _, edge := range g.player().Planning.Edges() {
 		hovered := g.hoveredConnection != nil && *g.hoveredConnection == edge
 		selected := g.selectedConnection != nil && *g.selectedConnection == edge
 
@@ -689,16 +895,22 @@ func (g *Game) drawVillageCalculation(screen *ebiten.Image, result *VillageCalcu
 		DrawStationConnection(screen, g.toScreen, edge.One, edge.Two, 0, true, c)
 	}
 
-	// walk through the edges we've constructed and paint them
-	for _, edge := range g.acceptedGraph.Edges() {
-		offset := time.Now().Sub(edge.Created)
-		DrawStationConnection(screen, g.toScreen, edge.One, edge.Two, offset, false, StationColorConstructed.Stroke)
+	// walk through the edges constructed by each player and paint them
+	for _, player := range g.match.Turn.Players {
+		for _, edge := range player.Graph.Edges() {
+			offset := time.Now().Sub(edge.Created)
+			DrawStationConnection(screen, g.toScreen, edge.One, edge.Two, offset, false, player.Color.Stroke)
+		}
+	}
+
+	if g.comparison {
+		g.drawComparison(screen)
 	}
 
 	if g.debug {
 		// remaining best solution
 		if ebiten.IsKeyPressed(ebiten.KeyS) {
-			mst := BuildMST(g.acceptedGraph)
+			mst := BuildMST(g.match.Accepted)
 			for _, edge := range mst.Edges() {
 				DrawStationConnection(screen, g.toScreen, edge.One, edge.Two, 0, true, DebugColor)
 			}
@@ -764,6 +976,21 @@ func (g *Game) drawVillageCalculation(screen *ebiten.Image, result *VillageCalcu
 	}
 }
 
+func (g *Game) drawVillagePreviews(screen *ebiten.Image) {
+	for _, preview := range g.villagePreviews {
+		FillPath(screen, pathOf(preview.Hull, true), g.toScreen, color.RGBA{R: 0xb0, G: 0x89, B: 0xab, A: 0x20})
+
+		// names fade in once the village stops growing
+		alpha := Clamp((g.now.Sub(preview.Changed).Seconds()-1)/0.5, 0, 1)
+		if alpha <= 0 {
+			continue
+		}
+
+		pos := TransformVec(g.toScreen, preview.Center)
+		DrawTextCenter(screen, preview.Name, Font16, pos, scaleColorWithAlpha(DarkTextColor, alpha))
+	}
+}
+
 func (g *Game) stationColorOf(station *Station) (StationColor, bool) {
 	// if the circle is hovered, select a different color palette
 	switch {
@@ -773,10 +1000,14 @@ func (g *Game) stationColorOf(station *Station) (StationColor, bool) {
 	case g.hoveredStation == station:
 		return StationColorHover, true
 
-	case len(g.acceptedGraph.EdgesOf(station)) > 0:
+	case g.match.Accepted.HasConnections(station):
+		if owner := g.match.OwnerOf(station); owner != nil {
+			return owner.Color, false
+		}
+
 		return StationColorConstructed, false
 
-	case len(g.planningGraph.EdgesOf(station)) > 0:
+	case g.player().Planning.HasConnections(station):
 		return StationColorPlanned, false
 
 	default:
@@ -786,11 +1017,11 @@ func (g *Game) stationColorOf(station *Station) (StationColor, bool) {
 
 func (g *Game) DrawDebugText(screen *ebiten.Image) {
 	pos := vecSplat(32)
-	t := fmt.Sprintf("%1.1f fps, seed %d", ebiten.ActualFPS(), g.seed)
+	t := fmt.Sprintf("%1.1f fps, level %s", ebiten.ActualFPS(), LevelId(g.seed, g.config))
 	DrawTextLeft(screen, t, Font16, pos, DebugColor)
 
 	pos.Y += 24
-	t = fmt.Sprintf("Street Segments: %d", len(g.streetsGenerator.segments))
+	t = fmt.Sprintf("Street Segments: %d", len(g.generator.Streets.segments))
 	DrawTextLeft(screen, t, Font16, pos, DebugColor)
 
 	if !g.streetGenerationEndTime.IsZero() {
@@ -818,14 +1049,10 @@ func (g *Game) DrawDebugText(screen *ebiten.Image) {
 }
 
 func (g *Game) updateTransform() {
-	// base size, used for scaling
-	worldWidth := 32000.0
+	g.toScreen = g.cameraTransform(g.camera)
 
-	scale := float64(g.screenWidth) / worldWidth
-	g.worldScale = scale
-
-	g.toScreen = ebiten.GeoM{}
-	g.toScreen.Scale(scale, scale)
+	// the scale of the transform, the same for both axis
+	g.worldScale = g.toScreen.Element(0, 0)
 
 	// create an inverse of the transform to transform from screen coordinates
 	// to world coordinates
@@ -834,7 +1061,12 @@ func (g *Game) updateTransform() {
 }
 
 func (g *Game) updateWinCondition() {
-	if g.lost || g.won || len(g.acceptedGraph.Stations) == 0 {
+	if g.lost || g.won || len(g.match.Accepted.Stations) == 0 {
+		return
+	}
+
+	if g.match.Turn.IsMultiplayer() {
+		// the game ends once no player can take a turn anymore, see endTurn
 		return
 	}
 
@@ -845,8 +1077,8 @@ func (g *Game) updateWinCondition() {
 	// check if there is no station left that we can connect
 	// to any connected station
 outer:
-	for _, station := range g.acceptedGraph.Stations {
-		if g.acceptedGraph.HasConnections(station) {
+	for _, station := range g.match.Accepted.Stations {
+		if g.match.Accepted.HasConnections(station) {
 			hasConnected = true
 			continue
 		}
@@ -855,12 +1087,12 @@ outer:
 
 		// station is not yet connected, check for the chepest connection to
 		// an already connected node
-		for _, other := range g.acceptedGraph.Stations {
-			if !g.acceptedGraph.HasConnections(other) {
+		for _, other := range g.match.Accepted.Stations {
+			if !g.match.Accepted.HasConnections(other) {
 				continue
 			}
 
-			if priceOf(station, other) < g.stats.CoinsAvailable() {
+			if priceOf(station, other) < g.player().Stats.CoinsAvailable() {
 				// reachable
 				actionAvailable = true
 				break outer
@@ -873,13 +1105,14 @@ outer:
 	// no unconnected station.
 	if !hasUnconnected {
 		// check if we have seen all stations
-		if g.allStationsConnected() {
+		if g.match.Accepted.IsConnected() {
 			// player has won
 			g.won = true
+			g.finishLevel()
 
 			g.audio.Play(g.audio.Win)
 
-			g.dialogStack.Push(Dialog{
+			dialog := Dialog{
 				Id:    "won",
 				Modal: true,
 				Texts: []Text{
@@ -923,13 +1156,33 @@ outer:
 				},
 
 				Buttons: []*Button{
+					NewButton("Report", HudButtonColors).WithAutoSize().WithOnClick(g.showResults),
+
+					// share the map with friends
+					g.copyShareCodeButton(),
+
 					NewButton("Onwards!", AcceptButtonColors).WithOnClick(func() {
 						g.resetOnUpdate = &ResetOnUpdate{
 							NextSeed: g.nextSeed(g.isSimple),
 						}
 					}),
 				},
-			})
+			}
+
+			if g.isTimeAttack() {
+				bonus := g.timeBonus()
+				g.player().Stats.Score += bonus
+
+				// mention the bonus, the loading text needs to stay the last one
+				dialog.Texts = slices.Insert(dialog.Texts, len(dialog.Texts)-1, Text{
+					Face:   Font16,
+					Text:   fmt.Sprintf("And with %s left on the clock, that’s %d bonus points on top!", formatCountdown(g.timeRemaining), bonus),
+					Color:  DarkTextColor,
+					Offset: Vec{Y: 8},
+				})
+			}
+
+			g.dialogStack.Push(dialog)
 
 			g.reportScore()
 		}
@@ -940,12 +1193,13 @@ outer:
 	// if there is no further action available, the player has lost
 	if hasConnected && !actionAvailable {
 		g.lost = true
+		g.finishLevel()
 
 		g.audio.Play(g.audio.Lose)
 
-		solution := BuildMST(g.acceptedGraph)
-		missingConnections := len(solution.Edges()) - len(g.acceptedGraph.Edges())
-		missingCoins := solution.TotalPrice() - g.acceptedGraph.TotalPrice() - g.stats.CoinsAvailable()
+		solution := BuildMST(g.match.Accepted)
+		missingConnections := len(solution.Edges()) - len(g.match.Accepted.Edges())
+		missingCoins := solution.TotalPrice() - g.match.Accepted.TotalPrice() - g.player().Stats.CoinsAvailable()
 
 		g.dialogStack.Push(Dialog{
 			Id:    "lost",
@@ -990,6 +1244,8 @@ outer:
 			},
 
 			Buttons: []*Button{
+				NewButton("Report", HudButtonColors).WithAutoSize().WithOnClick(g.showResults),
+
 				NewButton("Have another go", AcceptButtonColors).WithAutoSize().WithOnClick(func() {
 					g.resetOnUpdate = &ResetOnUpdate{
 						NextSeed: g.seed,
@@ -1008,59 +1264,8 @@ outer:
 	}
 }
 
-func (g *Game) allStationsConnected() bool {
-	graph := g.acceptedGraph
-
-	var seen Set[*Station]
-
-	queue := make([]*Station, 0, len(graph.Stations))
-
-	initial := graph.Stations[0]
-	queue = append(queue, initial)
-	seen.Insert(initial)
-
-	for idx := 0; idx < len(queue); idx++ {
-		current := queue[idx]
-
-		for _, edge := range graph.EdgesOf(current) {
-			other := edge.OtherStation(current)
-
-			if seen.Has(other) {
-				continue
-			}
-
-			queue = append(queue, other)
-			seen.Insert(other)
-		}
-	}
-
-	return seen.Len() == len(graph.Stations)
-}
-
 func (g *Game) nextSeed(wantSimple bool) uint64 {
-	simple := []uint64{
-		47,
-		49,
-		51,
-		53,
-		63,
-		68,
-		79,
-	}
-
-	hard := []uint64{
-		17,
-		18,
-		48,
-		35,
-		62,
-		64,
-		67,
-		88,
-		92,
-	}
-
-	levels := iff(wantSimple, simple, hard)
+	levels := iff(wantSimple, simpleLevels, hardLevels)
 
 	nextSeed := levels[0]
 
@@ -1077,11 +1282,31 @@ func (g *Game) nextSeed(wantSimple bool) uint64 {
 
 func (g *Game) reportScore() {
 	playerName := PlayerName()
-	g.leaderboard = ReportHighscore(g.seed, playerName, g.stats.Score)
+
+	if g.edits != nil {
+		// edited levels have no public leaderboard, the budget might have been raised
+		own := Leaderboard{Items: []LeaderboardItem{{Player: playerName, Score: g.player().Stats.Score}}}
+		g.leaderboard = AsyncTaskContext(context.Background(), func(context.Context, func(struct{})) (Leaderboard, error) {
+			return own, nil
+		})
+
+		return
+	}
+
+	g.leaderboard = ReportHighscore(g.leaderboardNamespace(), LevelId(g.seed, g.config), playerName, g.player().Stats.Score)
 }
 
 func (g *Game) checkLeaderboardResponse() {
-	if result := g.leaderboard.GetOnce(); result != nil {
+	result := g.leaderboard.GetOnce()
+
+	if err := g.leaderboard.ErrOnce(); err != nil {
+		fmt.Printf("[err] loading leaderboard failed: %s\n", err)
+
+		// show at least the score of the player
+		result = &Leaderboard{Items: []LeaderboardItem{{Player: PlayerName(), Score: g.player().Stats.Score}}}
+	}
+
+	if result != nil {
 		dialog := g.dialogStack.ById("won")
 		if dialog == nil {
 			return
@@ -1124,25 +1349,17 @@ func (g *Game) checkLeaderboardResponse() {
 	}
 }
 
-func (g *Game) villageIsConnected(village *Village) bool {
-	for _, station := range g.acceptedGraph.Stations {
-		if station.Village == village && g.acceptedGraph.HasConnections(station) {
-			return true
-		}
-	}
-
-	return false
+// player returns the player that is currently active
+func (g *Game) player() *Player {
+	return g.match.Turn.Active()
 }
 
 func (g *Game) showSettings() {
 	g.menu = g.menu[:0]
 
-	var maxWidth float64
-
 	add := func(btn *Button) *Button {
 		btn = btn.WithAutoSize()
 		btn.Alpha = 0
-		maxWidth = max(maxWidth, btn.Size.X)
 		g.menu = append(g.menu, btn)
 		return btn
 	}
@@ -1160,6 +1377,45 @@ func (g *Game) showSettings() {
 		mute.Text = muteText()
 	}
 
+	minimapText := func() string { return iff(g.minimap.Hidden, "Show minimap", "Hide minimap") }
+	minimap := add(NewButton(minimapText(), HudButtonColors))
+	minimap.OnClick = func() {
+		g.minimap.Toggle()
+		minimap.Text = minimapText()
+	}
+
+	add(NewButton(iff(g.timeAttack, "Relaxed pace", "Time attack"), HudButtonColors)).WithOnClick(func() {
+		g.timeAttack = !g.timeAttack
+
+		// the clock only ticks for a single player
+		g.resetOnUpdate = &ResetOnUpdate{
+			NextSeed:   g.seed,
+			WantSimple: g.isSimple,
+			Players:    iff(g.timeAttack, 1, 0),
+		}
+	})
+
+	add(NewButton("Enter level code", HudButtonColors)).WithOnClick(func() {
+		g.menu = nil
+		g.showShareCodeDialog()
+	})
+
+	if g.villagesAsync.Get() != nil {
+		add(NewButton("Export map", HudButtonColors)).WithOnClick(func() {
+			g.menu = nil
+			g.exportSvg()
+		})
+	}
+
+	if g.villagesAsync.Get() != nil && g.net == nil {
+		add(NewButton("Level editor", HudButtonColors)).WithOnClick(g.openLevelEditor)
+	}
+
+	add(NewButton("Hot-seat game", HudButtonColors)).WithOnClick(func() {
+		g.menu = nil
+		g.showPlayerCountDialog()
+	})
+
 	add(NewButton("Simple level", HudButtonColors)).WithOnClick(func() {
 		g.resetOnUpdate = &ResetOnUpdate{
 			WantSimple: true,
@@ -1174,8 +1430,7 @@ func (g *Game) showSettings() {
 		}
 	})
 
-	pos := Vec{X: float64(g.screenWidth) - 32 - maxWidth, Y: 64 + 24}
-	LayoutButtonsColumn(pos, 8, g.menu...)
+	g.layoutMenu()
 
 	for idx, button := range g.menu {
 		delay := time.Duration(idx) * 50 * time.Millisecond
@@ -1183,6 +1438,17 @@ func (g *Game) showSettings() {
 	}
 }
 
+func (g *Game) layoutMenu() {
+	var maxWidth float64
+	for _, button := range g.menu {
+		maxWidth = max(maxWidth, button.Size.X)
+	}
+
+	// right align the menu below the settings button
+	pos := Vec{X: float64(g.screenWidth) - 32 - maxWidth, Y: 64 + 24}
+	LayoutButtonsColumn(pos, 8, g.menu...)
+}
+
 func (g *Game) slideIn(button *Button, delay time.Duration) {
 	g.tweens.Add(tween.Delay(delay, tween.Concurrent(
 		&tween.Simple{
@@ -1200,3 +1466,56 @@ func (g *Game) slideIn(button *Button, delay time.Duration) {
 	button.Alpha = 0
 	button.Position.X -= 16
 }
+
+// exportSvg saves the map with the network of all players
+func (g *Game) exportSvg() {
+	level := g.villagesAsync.Get()
+	if level == nil {
+		// nothing worth printing yet
+		return
+	}
+
+	svg := RenderSvg(MapExport{
+		Label:   "Level: " + LevelId(g.seed, g.config),
+		Streets: g.generator.Streets.Segments(),
+		Level:   *level,
+		Match:   &g.match,
+	})
+
+	name := fmt.Sprintf("union-station-%s.svg", LevelId(g.seed, g.config))
+	if err := SaveExport(name, "image/svg+xml", svg); err != nil {
+		fmt.Printf("[err] export map: %s\n", err)
+	}
+}
+
+// time in seconds a bot waits before each action, so humans can follow along
+const agentThinkTime = 0.75
+
+// updateAgent lets the bot of the active player take its turn
+func (g *Game) updateAgent(dt float64) {
+	player := g.player()
+
+	if player.Agent == nil || g.won || g.lost || len(g.match.Accepted.Stations) == 0 {
+		g.agentDelay = 0
+		return
+	}
+
+	g.agentDelay += dt
+	if g.agentDelay < agentThinkTime {
+		return
+	}
+
+	g.agentDelay = 0
+
+	action, ok := player.Agent.Act(&g.match, player)
+	if !ok {
+		if g.match.Turn.IsMultiplayer() && g.net == nil {
+			// the bot passes, let the next player continue
+			g.endTurn()
+		}
+
+		return
+	}
+
+	g.perform(action)
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"slices"
+)
+
+// GenerationConfig holds all parameters of the level generation.
+// The same seed with the same config always produces the very same level.
+type GenerationConfig struct {
+	// frequency of the noise driving the population density, higher means smaller towns
+	PopulationFrequency float64 `json:"populationFrequency"`
+
+	// frequency of the noise the rivers follow
+	TerrainFrequency float64 `json:"terrainFrequency"`
+
+	// number of rivers crossing the world
+	RiverCount int `json:"riverCount"`
+
+	// minimum distance of the first street to the rivers in meters
+	StartRiverDistance float64 `json:"startRiverDistance"`
+
+	// maximum number of tributaries flowing into each river
+	TributaryCount int `json:"tributaryCount"`
+
+	// chance for a river to split into a delta before it leaves the world
+	DeltaChance float64 `json:"deltaChance"`
+
+	// chance for a river without a delta to widen into an estuary
+	EstuaryChance float64 `json:"estuaryChance"`
+
+	// streets ending this near to another street are connected to it
+	ConnectThreshold float64 `json:"connectThreshold"`
+
+	// streets this near to each other belong to the same village
+	ClusterDistance float64 `json:"clusterDistance"`
+
+	// a cluster needs more segments than this to be called a village
+	MinClusterSegments int `json:"minClusterSegments"`
+
+	// no stations are placed this near to the edge of the world
+	ClipThreshold float64 `json:"clipThreshold"`
+
+	// money available relative to the price of the optimal network
+	BudgetFactor float64 `json:"budgetFactor"`
+
+	// number of lakes, fewer if there is no space left
+	LakeCount int `json:"lakeCount"`
+
+	// chance for the sea along one side of the world
+	CoastChance float64 `json:"coastChance"`
+
+	// roughly the part of the world covered by forests
+	ForestCoverage float64 `json:"forestCoverage"`
+
+	// village names are generated from the data file of this theme in assets/names
+	NameTheme string `json:"nameTheme"`
+}
+
+// DefaultGenerationConfig produces the classic levels, including the curated ones
+var DefaultGenerationConfig = GenerationConfig{
+	PopulationFrequency: 0.0008,
+	TerrainFrequency:    0.0001,
+	RiverCount:          2,
+	StartRiverDistance:  5_000,
+	TributaryCount:      2,
+	DeltaChance:         0.3,
+	EstuaryChance:       0.3,
+	ConnectThreshold:    30,
+	ClusterDistance:     100,
+	MinClusterSegments:  32,
+	ClipThreshold:       1_500,
+	BudgetFactor:        1.05,
+	LakeCount:           2,
+	CoastChance:         0.3,
+	ForestCoverage:      0.2,
+	NameTheme:           "english",
+}
+
+type GenerationPreset struct {
+	Name   string
+	Config GenerationConfig
+}
+
+var GenerationPresets = []GenerationPreset{
+	{
+		Name:   "classic",
+		Config: DefaultGenerationConfig,
+	},
+	{
+		// few large villages, far apart and a bit more money to reach them
+		Name: "sparse-countryside",
+		Config: GenerationConfig{
+			PopulationFrequency: 0.0006,
+			TerrainFrequency:    0.0001,
+			RiverCount:          1,
+			StartRiverDistance:  5_000,
+			TributaryCount:      1,
+			DeltaChance:         0.2,
+			EstuaryChance:       0.3,
+			ConnectThreshold:    30,
+			ClusterDistance:     100,
+			MinClusterSegments:  40,
+			ClipThreshold:       1_500,
+			BudgetFactor:        1.1,
+			LakeCount:           3,
+			CoastChance:         0.2,
+			ForestCoverage:      0.3,
+			NameTheme:           "english",
+		},
+	},
+	{
+		// many small villages close to each other, every coin counts
+		Name: "dense-county",
+		Config: GenerationConfig{
+			PopulationFrequency: 0.0012,
+			TerrainFrequency:    0.0001,
+			RiverCount:          2,
+			StartRiverDistance:  3_000,
+			TributaryCount:      2,
+			DeltaChance:         0.3,
+			EstuaryChance:       0.3,
+			ConnectThreshold:    30,
+			ClusterDistance:     80,
+			MinClusterSegments:  24,
+			ClipThreshold:       1_500,
+			BudgetFactor:        1.02,
+			LakeCount:           1,
+			CoastChance:         0.3,
+			ForestCoverage:      0.1,
+			NameTheme:           "english",
+		},
+	},
+	{
+		// lots of winding rivers, bridges are hard to avoid
+		Name: "river-delta",
+		Config: GenerationConfig{
+			PopulationFrequency: 0.0008,
+			TerrainFrequency:    0.0002,
+			RiverCount:          5,
+			StartRiverDistance:  2_500,
+			TributaryCount:      3,
+			DeltaChance:         0.8,
+			EstuaryChance:       0.5,
+			ConnectThreshold:    30,
+			ClusterDistance:     100,
+			MinClusterSegments:  32,
+			ClipThreshold:       1_500,
+			BudgetFactor:        1.1,
+			LakeCount:           2,
+			CoastChance:         1.0,
+			ForestCoverage:      0.15,
+			NameTheme:           "english",
+		},
+	},
+}
+
+func GenerationPresetNames() []string {
+	var names []string
+	for _, preset := range GenerationPresets {
+		names = append(names, preset.Name)
+	}
+
+	return names
+}
+
+func GenerationPresetByName(name string) (GenerationConfig, bool) {
+	idx := slices.IndexFunc(GenerationPresets, func(preset GenerationPreset) bool {
+		return preset.Name == name
+	})
+
+	if idx < 0 {
+		return GenerationConfig{}, false
+	}
+
+	return GenerationPresets[idx].Config, true
+}
+
+// Hash identifies the config, levels generated with different configs must never be mixed up
+func (c GenerationConfig) Hash() uint32 {
+	// fields are always encoded in the same order
+	buf, _ := json.Marshal(c)
+
+	h := fnv.New32a()
+	_, _ = h.Write(buf)
+	return h.Sum32()
+}
+
+// LevelId identifies a level by its seed, config and the version of the generator.
+// It keys the leaderboards, scores of a map generated differently are never mixed up.
+// The config is left out for the classic levels to keep the ids short.
+func LevelId(seed uint64, config GenerationConfig) string {
+	if config == DefaultGenerationConfig {
+		return fmt.Sprintf("%d-v%d", seed, GeneratorVersion)
+	}
+
+	return fmt.Sprintf("%d-%08x-v%d", seed, config.Hash(), GeneratorVersion)
+}
+
+// LevelVariant identifies everything except the seed that changes the level generated
+// for a seed, the generation config, the version of the generator and the edits.
+func LevelVariant(config GenerationConfig, edits *LevelEdits) string {
+	variant := fmt.Sprintf("%08x-v%d", config.Hash(), GeneratorVersion)
+	if edits != nil {
+		variant += fmt.Sprintf("-%08x", edits.Hash())
+	}
+
+	return variant
+}
+
+// LevelFile describes a level to play, it can be passed on the command line
+type LevelFile struct {
+	Seed uint64 `json:"seed"`
+
+	// name of a preset the config is based on, defaults to classic
+	Preset string `json:"preset"`
+
+	// overrides individual values of the preset
+	Config json.RawMessage `json:"config"`
+
+	// changes made in the level editor, if any
+	Edits *LevelEdits `json:"edits,omitempty"`
+
+	// the level the edits were made for, they are not applied to any other level
+	GeneratorVersion int    `json:"generatorVersion,omitempty"`
+	Variant          string `json:"variant,omitempty"`
+}
+
+// LevelFileRef is the path of a level file together with the level saved in it
+type LevelFileRef struct {
+	Path   string
+	Seed   uint64
+	Config GenerationConfig
+}
+
+// Holds checks if the file was saved for the given level, it must not be overwritten with another one
+func (r *LevelFileRef) Holds(seed uint64, config GenerationConfig) bool {
+	return r != nil && r.Seed == seed && r.Config == config
+}
+
+// LoadLevelFile reads the seed, the generation config and the edits from a json file
+func LoadLevelFile(path string) (uint64, GenerationConfig, *LevelEdits, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return 0, GenerationConfig{}, nil, err
+	}
+
+	var file LevelFile
+	if err := json.Unmarshal(buf, &file); err != nil {
+		return 0, GenerationConfig{}, nil, fmt.Errorf("parse level file %q: %w", path, err)
+	}
+
+	config := DefaultGenerationConfig
+
+	if file.Preset != "" {
+		preset, ok := GenerationPresetByName(file.Preset)
+		if !ok {
+			return 0, GenerationConfig{}, nil, fmt.Errorf("unknown preset %q, expected one of %v", file.Preset, GenerationPresetNames())
+		}
+
+		config = preset
+	}
+
+	if len(file.Config) > 0 {
+		// values not given in the file keep the value of the preset
+		if err := json.Unmarshal(file.Config, &config); err != nil {
+			return 0, GenerationConfig{}, nil, fmt.Errorf("parse config in level file %q: %w", path, err)
+		}
+	}
+
+	if file.Edits != nil {
+		file.Edits.seed = file.Seed
+		file.Edits.generatorVersion = file.GeneratorVersion
+		file.Edits.variant = file.Variant
+
+		if dropped := file.Edits.removeInvalidStations(); dropped > 0 {
+			fmt.Printf("[err] dropped %d stations of unknown villages from level file %q\n", dropped, path)
+		}
+	}
+
+	return file.Seed, config, file.Edits, nil
+}
+
+// MarshalLevelFile encodes a level file with the complete config, it does not depend on any preset
+func MarshalLevelFile(seed uint64, config GenerationConfig, edits *LevelEdits) ([]byte, error) {
+	configJson, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+
+	file := LevelFile{Seed: seed, Config: configJson, Edits: edits}
+	if edits != nil {
+		file.GeneratorVersion = edits.generatorVersion
+		file.Variant = edits.variant
+	}
+
+	return json.MarshalIndent(file, "", "  ")
+}
@@ -3,12 +3,14 @@ module github.com/oliverbestmann/union-station
 go 1.24.3
 
 require (
+	github.com/coder/websocket v1.8.14
 	github.com/fogleman/ease v0.0.0-20170301025033-8da417bf1776
 	github.com/furui/fastnoiselite-go v0.0.0-20220802181908-5f37e99ef939
 	github.com/hajimehoshi/ebiten/v2 v2.8.8
 	github.com/neilotoole/streamcache v0.3.5
 	github.com/pkg/profile v1.7.0
 	github.com/quasilyte/gmath v0.0.0-20250621152721-92bf45e3b54d
+	golang.org/x/image v0.28.0
 )
 
 require (
@@ -23,7 +25,6 @@ require (
 	github.com/jezek/xgb v1.1.1 // indirect
 	github.com/neilotoole/fifomu v0.1.2 // indirect
 	github.com/stretchr/testify v1.10.0 // indirect
-	golang.org/x/image v0.28.0 // indirect
 	golang.org/x/sync v0.15.0 // indirect
 	golang.org/x/sys v0.33.0 // indirect
 	golang.org/x/text v0.26.0 // indirect
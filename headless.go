@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+)
+
+// PlayHeadless lets the agents play the level against each other, taking
+// turns like in a hot-seat game. Returns the match after the last turn.
+func PlayHeadless(level VillageCalculation, agents []Agent) *Match {
+	match := &Match{
+		Turn: TurnController{Players: NewPlayers(len(agents))},
+	}
+
+	for idx, player := range match.Turn.Players {
+		player.Agent = agents[idx]
+		player.Name = agents[idx].Name()
+	}
+
+	match.Start(level.Stations, level.Stats)
+
+	// stop once every agent has passed in a row
+	var passes int
+
+	for passes < len(agents) {
+		player := match.Turn.Active()
+
+		action, ok := player.Agent.Act(match, player)
+		if ok && match.Apply(player, action, time.Time{}) && action.Kind == ActionBuild {
+			passes = 0
+		} else {
+			passes += 1
+		}
+
+		if !match.Turn.IsMultiplayer() && match.Accepted.IsConnected() {
+			// a single player has won the level
+			break
+		}
+
+		if !match.Turn.Advance(match.CanAct) {
+			break
+		}
+	}
+
+	return match
+}
+
+// HeadlessExport selects the maps written in a headless run. Each map shows
+// the level with the network of the last match played on it.
+type HeadlessExport struct {
+	// directories the maps are written to, nothing is written if empty
+	SvgDir string
+	PngDir string
+
+	// width of the png images in pixels
+	PngWidth int
+}
+
+// RunHeadless plays the levels with the given bots, first alone and then against each other,
+// and writes the results to w. This is used to check the balance of new levels.
+func RunHeadless(w io.Writer, seeds []uint64, config GenerationConfig, agentNames []string, export HeadlessExport) error {
+	var agents []Agent
+
+	for _, name := range agentNames {
+		agent, ok := AgentByName(name)
+		if !ok {
+			return fmt.Errorf("unknown bot %q, expected one of %v", name, AgentNames)
+		}
+
+		agents = append(agents, agent)
+	}
+
+	for _, dir := range []string{export.SvgDir, export.PngDir} {
+		if dir == "" {
+			continue
+		}
+
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("create export directory: %w", err)
+		}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	for _, seed := range seeds {
+		startTime := time.Now()
+		generator, level := GenerateLevel(seed, config)
+
+		_, _ = fmt.Fprintf(tw, "Level %s\t%d stations\tbudget %s\tmst %s\tgenerated in %s\n",
+			LevelId(seed, config), len(level.Stations), level.Stats.CoinsTotal, level.Mst.TotalPrice(),
+			time.Since(startTime).Round(time.Millisecond),
+		)
+
+		var lastMatch *Match
+
+		for _, agent := range agents {
+			match := PlayHeadless(level, []Agent{agent})
+			result := iff(match.Accepted.IsConnected(), "won", "lost")
+			writePlayerResult(tw, match.Turn.Players[0], "alone, "+result)
+			lastMatch = match
+		}
+
+		if len(agents) > 1 {
+			match := PlayHeadless(level, agents)
+			for _, player := range match.Turn.Players {
+				writePlayerResult(tw, player, "in match")
+			}
+
+			lastMatch = match
+		}
+
+		mapExport := MapExport{
+			Label:   "Level " + LevelId(seed, config),
+			Streets: generator.Streets.Segments(),
+			Level:   level,
+			Match:   lastMatch,
+		}
+
+		if err := export.write(mapExport, LevelId(seed, config)); err != nil {
+			return err
+		}
+
+		_, _ = fmt.Fprintln(tw)
+	}
+
+	return tw.Flush()
+}
+
+func (e HeadlessExport) write(export MapExport, levelId string) error {
+	if e.SvgDir != "" {
+		path := filepath.Join(e.SvgDir, "level-"+levelId+".svg")
+		if err := os.WriteFile(path, RenderSvg(export), 0o644); err != nil {
+			return err
+		}
+	}
+
+	if e.PngDir != "" {
+		buf, err := RenderPng(export, e.PngWidth)
+		if err != nil {
+			return fmt.Errorf("render level %s: %w", levelId, err)
+		}
+
+		path := filepath.Join(e.PngDir, "level-"+levelId+".png")
+		if err := os.WriteFile(path, buf, 0o644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writePlayerResult(w io.Writer, player *Player, mode string) {
+	stats := player.Stats
+
+	_, _ = fmt.Fprintf(w, "  %s\t%s\tscore %d\tspent %s of %s\tconnected %d/%d\n",
+		player.Name, mode, stats.Score, stats.CoinsSpent, stats.CoinsTotal,
+		stats.StationsConnected, stats.StationsTotal,
+	)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"cmp"
+	. "github.com/quasilyte/gmath"
+	"math"
+	"slices"
+)
+
+// minimum population value of a town center that gets a highway
+const townCenterThreshold = 0.4
+
+// highways between town centers further apart are not planned
+const maxHighwayLength = 8_000
+
+// ConnectTownCenters plans highways between the centers of the towns.
+// The town centers are connected using a minimum spanning tree, the highways
+// are then generated segment by segment, like all other streets.
+func (gen *StreetGenerator) ConnectTownCenters() {
+	centers := gen.townCenters()
+
+	type link struct {
+		One, Two int
+		Length   float64
+	}
+
+	var links []link
+	for i := range centers {
+		for j := i + 1; j < len(centers); j++ {
+			length := centers[i].DistanceTo(centers[j])
+			if length < maxHighwayLength {
+				links = append(links, link{One: i, Two: j, Length: length})
+			}
+		}
+	}
+
+	slices.SortStableFunc(links, func(a, b link) int {
+		return cmp.Compare(a.Length, b.Length)
+	})
+
+	// kruskal on the indices of the centers
+	parent := make([]int, len(centers))
+	for idx := range parent {
+		parent[idx] = idx
+	}
+
+	find := func(idx int) int {
+		for parent[idx] != idx {
+			idx = parent[idx]
+		}
+
+		return idx
+	}
+
+	for _, link := range links {
+		rootOne, rootTwo := find(link.One), find(link.Two)
+		if rootOne == rootTwo {
+			continue
+		}
+
+		parent[rootTwo] = rootOne
+
+		start, target := centers[link.One], centers[link.Two]
+
+		gen.Push(PendingSegment{
+			Point:  start,
+			Angle:  start.AngleToPoint(target),
+			Type:   StreetTypeHighway,
+			Target: &target,
+		})
+	}
+}
+
+// townCenters finds the peaks of the population within the world
+func (gen *StreetGenerator) townCenters() []Vec {
+	const sampleDistance = 1_000
+	const mergeDistance = 500
+
+	var centers []Vec
+
+	for y := gen.Clip.Min.Y + sampleDistance/2; y < gen.Clip.Max.Y; y += sampleDistance {
+		for x := gen.Clip.Min.X + sampleDistance/2; x < gen.Clip.Max.X; x += sampleDistance {
+			center := gen.townCenterNear(Vec{X: x, Y: y})
+
+			if gen.PopulationAt(center) < townCenterThreshold || !gen.Clip.Contains(center) {
+				continue
+			}
+
+			// multiple samples climb up to the same peak
+			known := slices.ContainsFunc(centers, func(other Vec) bool {
+				return other.DistanceTo(center) < mergeDistance
+			})
+
+			if !known {
+				centers = append(centers, center)
+			}
+		}
+	}
+
+	return centers
+}
+
+// continueToTarget continues a highway towards the town center it is heading to
+func (gen *StreetGenerator) continueToTarget(prev PendingSegment, segment *Segment) {
+	if prev.Target == nil {
+		return
+	}
+
+	const arrivedDistance = 100
+
+	if segment.End.DistanceTo(*prev.Target) < arrivedDistance {
+		// arrived at the town center
+		return
+	}
+
+	gen.segmentsQueue.Push(PendingSegment{
+		PreviousSegment:        segment,
+		Point:                  segment.End,
+		Angle:                  segment.Angle(),
+		DistanceToPreviousFork: prev.DistanceToPreviousFork + segment.Length(),
+		Type:                   prev.Type,
+		AtStep:                 prev.AtStep + 10,
+		Target:                 prev.Target,
+	})
+}
+
+// nextVecToward steers the highway towards its target in a gentle curve
+func (gen *StreetGenerator) nextVecToward(pos Vec, prevAngle Rad, target Vec) Vec {
+	length := Randf(gen.rng, 50.0, 80.0)
+
+	// allow sharper turns near the target, so we do not circle around it.
+	// the turning circle must stay well inside the distance to the target.
+	maxTurn := Clamp(Rad(3*length/pos.DistanceTo(target)), DegToRad(5), DegToRad(45))
+
+	turn := Rad(math.Remainder(float64(pos.AngleToPoint(target)-prevAngle), 2*math.Pi))
+	angle := prevAngle + Clamp(turn, -maxTurn, maxTurn) + Randf(gen.rng, -DegToRad(1), DegToRad(1))
+
+	return pos.Add(Vec{X: length}.Rotated(angle))
+}
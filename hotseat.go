@@ -0,0 +1,163 @@
+//go:build !headless
+
+package main
+
+import (
+	"cmp"
+	"fmt"
+	. "github.com/quasilyte/gmath"
+	"slices"
+	"strconv"
+)
+
+// endTurn passes the turn to the next player. If no player can build
+// anymore, the game is over and the final scores are shown.
+func (g *Game) endTurn() {
+	if g.match.Turn.Advance(g.match.CanAct) {
+		return
+	}
+
+	g.won = true
+
+	g.audio.Play(g.audio.Win)
+
+	g.dialogStack.Push(g.hotSeatResultDialog())
+}
+
+func (g *Game) hotSeatResultDialog() Dialog {
+	ranking := slices.SortedStableFunc(slices.Values(g.match.Turn.Players), func(a, b *Player) int {
+		return cmp.Compare(b.Stats.Score, a.Stats.Score)
+	})
+
+	headline := ranking[0].Name + " takes the crown!"
+	if ranking[0].Stats.Score == ranking[1].Stats.Score {
+		headline = "A draw, how very British!"
+	}
+
+	texts := []Text{
+		{
+			Face:  Font24,
+			Text:  "That's the end of the line",
+			Color: DarkTextColor,
+		},
+		{
+			Face:   Font16,
+			Text:   "No engineer can afford another stretch of track. " + headline,
+			Color:  DarkTextColor,
+			Offset: Vec{Y: 8},
+		},
+	}
+
+	availableWidth := MeasureTexts(texts).X
+
+	for idx, player := range ranking {
+		yOffset := iff(idx == 0, 8.0, 0)
+
+		texts = append(texts, Text{
+			Face:   Font16,
+			Text:   player.Name,
+			Color:  player.Color.Stroke,
+			Height: new(float64),
+			Offset: Vec{Y: yOffset},
+		})
+
+		// manually right align with availableWidth
+		scoreStr := strconv.Itoa(player.Stats.Score)
+		x := availableWidth - MeasureText(Font16, scoreStr).X
+
+		texts = append(texts, Text{
+			Face:   Font16,
+			Text:   scoreStr,
+			Color:  player.Color.Stroke,
+			Offset: Vec{X: x},
+		})
+	}
+
+	if g.net != nil {
+		return Dialog{
+			Id:    "hot-seat-result",
+			Modal: true,
+			Texts: texts,
+			Buttons: []*Button{
+				// the race is over, continue alone
+				NewButton("Onwards!", AcceptButtonColors).WithAutoSize().WithOnClick(func() {
+					g.resetOnUpdate = &ResetOnUpdate{
+						NextSeed: g.nextSeed(g.isSimple),
+						Players:  1,
+					}
+				}),
+			},
+		}
+	}
+
+	return Dialog{
+		Id:    "hot-seat-result",
+		Modal: true,
+		Texts: texts,
+		Buttons: []*Button{
+			NewButton("Rematch", AcceptButtonColors).WithAutoSize().WithOnClick(func() {
+				g.resetOnUpdate = &ResetOnUpdate{
+					NextSeed: g.seed,
+				}
+			}),
+
+			NewButton("Onwards!", AcceptButtonColors).WithAutoSize().WithOnClick(func() {
+				g.resetOnUpdate = &ResetOnUpdate{
+					NextSeed: g.nextSeed(g.isSimple),
+				}
+			}),
+		},
+	}
+}
+
+// showPlayerCountDialog asks for the number of players taking turns on the current level
+func (g *Game) showPlayerCountDialog() {
+	var buttons []*Button
+
+	for count := 1; count <= len(PlayerColors); count++ {
+		text := fmt.Sprintf("%d players", count)
+		if count == 1 {
+			text = "Alone"
+		}
+
+		buttons = append(buttons, NewButton(text, AcceptButtonColors).WithAutoSize().WithOnClick(func() {
+			g.resetOnUpdate = &ResetOnUpdate{
+				NextSeed:   g.seed,
+				WantSimple: g.isSimple,
+				Players:    count,
+			}
+		}))
+	}
+
+	buttons = append(buttons, NewButton("Versus bot", AcceptButtonColors).WithAutoSize().WithOnClick(func() {
+		g.resetOnUpdate = &ResetOnUpdate{
+			NextSeed:   g.seed,
+			WantSimple: g.isSimple,
+			Players:    2,
+			Bots:       1,
+		}
+	}))
+
+	buttons = append(buttons, NewButton("Cancel", HudButtonColors).WithAutoSize().WithOnClick(func() {
+		g.dialogStack.CloseById("player-count")
+	}))
+
+	g.dialogStack.Push(Dialog{
+		Id:    "player-count",
+		Modal: true,
+		Texts: []Text{
+			{
+				Face:  Font24,
+				Text:  "How many engineers are playing?",
+				Color: DarkTextColor,
+			},
+			{
+				Face:   Font16,
+				Text:   "Take turns building on the same map. Stations belong to whoever connects them first.",
+				Color:  DarkTextColor,
+				Offset: Vec{Y: 8},
+			},
+		},
+		Buttons: buttons,
+	})
+}
@@ -1,3 +1,5 @@
+//go:build !headless
+
 package main
 
 import (
@@ -9,6 +11,7 @@ import (
 	"image/color"
 	"math"
 	"sync"
+	"time"
 )
 
 func (g *Game) drawHUD(screen *ebiten.Image) {
@@ -36,33 +39,66 @@ func (g *Game) drawHUD(screen *ebiten.Image) {
 		button.Draw(screen)
 	}
 
-	if g.stats.CoinsTotal > 0 {
-		msg := fmt.Sprintf("Budget: %d", g.stats.CoinsAvailable())
+	stats := g.player().Stats
+
+	if stats.CoinsTotal > 0 {
+		msg := fmt.Sprintf("Budget: %d", stats.CoinsAvailable())
 		g.hudRectangleWithIcon(screen, &pos, -1, msg, HudRectangleColor, assets.Coin())
 
-		if g.stats.CoinsPlanned > 0 {
+		if stats.CoinsPlanned > 0 {
 			// add some space between the rectangles
 			pos.X -= 16
 
-			msg := fmt.Sprintf("Planned: %d", g.stats.CoinsPlanned)
+			msg := fmt.Sprintf("Planned: %d", stats.CoinsPlanned)
 			g.hudRectangleWithIcon(screen, &pos, -1, msg, HudPlannedRectangleColor, assets.PlannedCoin())
 		}
 
-		if g.stats.StationsConnected > 0 {
+		if stats.StationsConnected > 0 {
 			// add some space between the rectangles
 			pos.X -= 16
 
-			msg := fmt.Sprintf("Connected %d of %d", g.stats.StationsConnected, g.stats.StationsTotal)
+			msg := fmt.Sprintf("Connected %d of %d", stats.StationsConnected, stats.StationsTotal)
 			g.hudRectangleWithIcon(screen, &pos, -1, msg, HudRectangleColor, nil)
 		}
 
 	}
 
-	if g.stats.Score > 0 {
+	if g.match.Turn.IsMultiplayer() {
+		pos := Vec{X: 16, Y: 16}
+
+		// one rectangle per player, the active player is highlighted
+		for _, player := range g.match.Turn.Players {
+			msg := fmt.Sprintf("%s: %d", player.Name, player.Stats.Score)
+			rectangleColor := player.Color.Stroke
+
+			if player != g.player() {
+				rectangleColor = scaleColorWithAlpha(rectangleColor, 0.5)
+			}
+
+			g.hudRectangleWithIcon(screen, &pos, 1, msg, rectangleColor, nil)
+
+			// add some space between the rectangles
+			pos.X += 16
+		}
+
+	} else {
 		pos := Vec{X: 16, Y: 16}
 
-		msg := fmt.Sprintf("Score: %d", g.stats.Score)
-		g.hudRectangleWithIcon(screen, &pos, 1, msg, HudRectangleColor, nil)
+		if stats.Score > 0 {
+			msg := fmt.Sprintf("Score: %d", stats.Score)
+			g.hudRectangleWithIcon(screen, &pos, 1, msg, HudRectangleColor, nil)
+
+			// add some space between the rectangles
+			pos.X += 16
+		}
+
+		if g.clockRunning || (g.isTimeAttack() && g.lost) {
+			// warn the player when time is running out
+			rectangleColor := iff(g.timeRemaining < 10*time.Second, HudAlertRectangleColor, HudRectangleColor)
+
+			msg := "Time: " + formatCountdown(g.timeRemaining)
+			g.hudRectangleWithIcon(screen, &pos, 1, msg, rectangleColor, nil)
+		}
 	}
 }
 
@@ -3,7 +3,11 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"math/rand/v2"
+	"net/url"
+	"strconv"
 	"strings"
 	"syscall/js"
 )
@@ -63,6 +67,109 @@ func PlayerName() (name string) {
 	return candidate
 }
 
+// ParseLaunchOptions reads the launch options from the query parameters of the page
+func ParseLaunchOptions() (opts LaunchOptions) {
+	opts.Config = DefaultGenerationConfig
+
+	defer func() { _ = recover() }()
+
+	search := js.Global().Get("location").Get("search").String()
+
+	query, err := url.ParseQuery(strings.TrimPrefix(search, "?"))
+	if err != nil {
+		return
+	}
+
+	opts.Relay = query.Get("relay")
+	opts.Lobby = query.Get("lobby")
+	opts.Seed, _ = strconv.ParseUint(query.Get("seed"), 10, 64)
+	opts.Bot = query.Get("bot")
+	opts.Versus = query.Get("versus")
+
+	if preset, ok := GenerationPresetByName(query.Get("preset")); ok {
+		opts.Config = preset
+	}
+
+	if theme := query.Get("names"); theme != "" {
+		opts.Config.NameTheme = theme
+	}
+
+	if opts.Lobby == "" {
+		opts.Lobby = "default"
+	}
+
+	return
+}
+
+// CopyToClipboard writes the text to the clipboard of the browser. The browser
+// may refuse it, done is called once the browser has decided.
+func CopyToClipboard(text string, done func(err error)) {
+	defer func() {
+		if r := recover(); r != nil {
+			done(fmt.Errorf("clipboard not available: %v", r))
+		}
+	}()
+
+	clipboard := js.Global().Get("navigator").Get("clipboard")
+	if clipboard.IsUndefined() {
+		done(errors.New("clipboard not available"))
+		return
+	}
+
+	var onSuccess, onFailure js.Func
+
+	release := func() {
+		onSuccess.Release()
+		onFailure.Release()
+	}
+
+	onSuccess = js.FuncOf(func(this js.Value, args []js.Value) any {
+		release()
+		done(nil)
+		return nil
+	})
+
+	onFailure = js.FuncOf(func(this js.Value, args []js.Value) any {
+		release()
+		done(fmt.Errorf("clipboard refused: %s", args[0].Call("toString").String()))
+		return nil
+	})
+
+	clipboard.Call("writeText", text).Call("then", onSuccess, onFailure)
+}
+
+// SaveExport lets the browser download an exported file
+func SaveExport(name string, mimeType string, buf []byte) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("download not available: %v", r)
+		}
+	}()
+
+	array := js.Global().Get("Uint8Array").New(len(buf))
+	js.CopyBytesToJS(array, buf)
+
+	blob := js.Global().Get("Blob").New([]any{array}, map[string]any{"type": mimeType})
+	href := js.Global().Get("URL").Call("createObjectURL", blob)
+	defer js.Global().Get("URL").Call("revokeObjectURL", href)
+
+	// clicking a link is the only way to start a download
+	link := js.Global().Get("document").Call("createElement", "a")
+	link.Set("href", href)
+	link.Set("download", name)
+	link.Call("click")
+
+	return nil
+}
+
+// levels are only cached in memory, the browser has no place for them
+func readCachedLevel(key string) ([]byte, bool) {
+	return nil, false
+}
+
+func writeCachedLevel(key string, buf []byte) {
+}
+
 var surnames = []string{
 	"Bennett",
 	"Pembroke",
@@ -1,11 +1,13 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/oliverbestmann/union-station/fetch"
 	"net/url"
 	"strconv"
+	"time"
 )
 
 type Leaderboard struct {
@@ -17,21 +19,30 @@ type LeaderboardItem struct {
 	Score  int    `json:"score"`
 }
 
-func ReportHighscore(seed uint64, player string, score int) Promise[Leaderboard, struct{}] {
+// namespaces of the leaderboards, each game mode ranks its scores separately
+const (
+	LeaderboardClassic    = "union-station:dev"
+	LeaderboardTimeAttack = "union-station:dev:time-attack"
+)
+
+const leaderboardTimeout = 15 * time.Second
+
+func ReportHighscore(namespace string, levelId string, player string, score int) Promise[Leaderboard, struct{}] {
 	values := url.Values{}
 	values.Set("player", player)
 	values.Set("score", strconv.Itoa(score))
 
-	seedStr := strconv.Itoa(int(seed))
-	uri := "https://highscore.narf.zone/games/union-station:dev:" + seedStr + "?" + values.Encode()
+	uri := "https://highscore.narf.zone/games/" + namespace + ":" + levelId + "?" + values.Encode()
 
-	return AsyncTask(func(yield func(struct{})) (result Leaderboard) {
-		err := json.NewDecoder(fetch.Post(uri)).Decode(&result.Items)
-		if err != nil {
-			fmt.Printf("[err] decoding leaderboard response failed: %s", err)
-			result.Items = []LeaderboardItem{{Player: player, Score: score}}
+	request := AsyncTaskContext(context.Background(), func(ctx context.Context, yield func(struct{})) (Leaderboard, error) {
+		var result Leaderboard
+		if err := json.NewDecoder(fetch.Post(uri)).Decode(&result.Items); err != nil {
+			return Leaderboard{}, fmt.Errorf("decode leaderboard response: %w", err)
 		}
 
-		return
+		return result, nil
 	})
+
+	// do not keep the player waiting forever
+	return Timeout(request, leaderboardTimeout)
 }
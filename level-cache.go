@@ -0,0 +1,264 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	. "github.com/quasilyte/gmath"
+	"slices"
+	"sync"
+)
+
+// GeneratorVersion must be increased whenever the level generation changes.
+// Cached levels of other versions are discarded, and the level ids change
+// so that the scores of the old levels end up on their own leaderboards.
+const GeneratorVersion = 2
+
+// number of levels kept in memory
+const levelCacheSize = 4
+
+// LevelCache keeps the recently generated levels, playing a level
+// again does not need to generate it again. Native builds also keep
+// the levels on disk.
+type LevelCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedLevel
+
+	// keys of the entries, the most recently used one last
+	recent []string
+}
+
+type cachedLevel struct {
+	Generator *LevelGenerator
+	Level     VillageCalculation
+}
+
+var levelCache = &LevelCache{entries: map[string]cachedLevel{}}
+
+func levelCacheKey(seed uint64, config GenerationConfig) string {
+	return fmt.Sprintf("%d-%08x-v%d", seed, config.Hash(), GeneratorVersion)
+}
+
+// Get returns the generator with all streets generated and the villages of the level
+func (c *LevelCache) Get(seed uint64, worldSize Rect, config GenerationConfig) (*LevelGenerator, VillageCalculation, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := levelCacheKey(seed, config)
+
+	if entry, ok := c.entries[key]; ok {
+		c.touch(key)
+		return entry.Generator, entry.Level, true
+	}
+
+	buf, ok := readCachedLevel(key)
+	if !ok {
+		return nil, VillageCalculation{}, false
+	}
+
+	var data levelData
+	if err := json.Unmarshal(buf, &data); err != nil {
+		fmt.Printf("[err] discarding cached level %s: %s\n", key, err)
+		return nil, VillageCalculation{}, false
+	}
+
+	if data.Version != GeneratorVersion {
+		// written by a different version of the generator
+		return nil, VillageCalculation{}, false
+	}
+
+	// the terrain is quick to generate, only the streets and villages are cached
+	generator := NewLevelGenerator(seed, worldSize, config)
+	terrain := generator.Terrain.Terrain()
+
+	segments, level := data.decode(&terrain)
+	generator.Streets.Restore(segments)
+
+	c.insert(key, cachedLevel{Generator: generator, Level: level})
+
+	return generator, level, true
+}
+
+// Put adds a level once all its streets and villages are generated
+func (c *LevelCache) Put(seed uint64, config GenerationConfig, generator *LevelGenerator, level VillageCalculation) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := levelCacheKey(seed, config)
+
+	if _, ok := c.entries[key]; ok {
+		// level came from the cache
+		c.touch(key)
+		return
+	}
+
+	c.insert(key, cachedLevel{Generator: generator, Level: level})
+
+	buf, err := json.Marshal(encodeLevel(generator.Streets.Segments(), level))
+	if err != nil {
+		fmt.Printf("[err] encode level %s: %s\n", key, err)
+		return
+	}
+
+	writeCachedLevel(key, buf)
+}
+
+func (c *LevelCache) insert(key string, entry cachedLevel) {
+	c.entries[key] = entry
+	c.touch(key)
+
+	// forget the least recently used levels
+	for len(c.recent) > levelCacheSize {
+		delete(c.entries, c.recent[0])
+		c.recent = c.recent[1:]
+	}
+}
+
+func (c *LevelCache) touch(key string) {
+	c.recent = slices.DeleteFunc(c.recent, func(other string) bool { return other == key })
+	c.recent = append(c.recent, key)
+}
+
+// levelData is the serialized form of a level, pointers are replaced by indices
+type levelData struct {
+	Version  int
+	Segments []segmentData
+	Villages []villageData
+	Stations []stationData
+	Mst      [][2]int
+	Stats    Stats
+	RNGCheck int
+}
+
+type segmentData struct {
+	Start, End  Vec
+	Type        StreetType
+	Connections []int
+}
+
+type villageData struct {
+	Name            string
+	Hull            []Vec
+	Segments        []int
+	PopulationCount int
+	FunFact         string
+}
+
+type stationData struct {
+	Position Vec
+	Village  int
+}
+
+func encodeLevel(segments []*Segment, level VillageCalculation) levelData {
+	data := levelData{
+		Version:  GeneratorVersion,
+		Stats:    level.Stats,
+		RNGCheck: level.RNGCheck,
+	}
+
+	segmentIndices := indicesOf(segments)
+	for _, segment := range segments {
+		var connections []int
+		for _, connected := range segment.Connections {
+			connections = append(connections, segmentIndices[connected])
+		}
+
+		data.Segments = append(data.Segments, segmentData{
+			Start:       segment.Start,
+			End:         segment.End,
+			Type:        segment.Type,
+			Connections: connections,
+		})
+	}
+
+	for _, village := range level.Villages {
+		var indices []int
+		for _, segment := range village.Segments {
+			indices = append(indices, segmentIndices[segment])
+		}
+
+		data.Villages = append(data.Villages, villageData{
+			Name:            village.Name,
+			Hull:            village.Hull,
+			Segments:        indices,
+			PopulationCount: village.PopulationCount,
+			FunFact:         village.FunFact,
+		})
+	}
+
+	villageIndices := indicesOf(level.Villages)
+	for _, station := range level.Stations {
+		data.Stations = append(data.Stations, stationData{
+			Position: station.Position,
+			Village:  villageIndices[station.Village],
+		})
+	}
+
+	stationIndices := indicesOf(level.Stations)
+	for _, edge := range level.Mst.Edges() {
+		data.Mst = append(data.Mst, [2]int{stationIndices[edge.One], stationIndices[edge.Two]})
+	}
+
+	return data
+}
+
+func (data *levelData) decode(terrain *Terrain) ([]*Segment, VillageCalculation) {
+	segments := make([]*Segment, len(data.Segments))
+	for idx, segment := range data.Segments {
+		segments[idx] = &Segment{
+			Line: Line{Start: segment.Start, End: segment.End},
+			Type: segment.Type,
+		}
+	}
+
+	for idx, segment := range data.Segments {
+		for _, connected := range segment.Connections {
+			segments[idx].Connections = append(segments[idx].Connections, segments[connected])
+		}
+	}
+
+	level := VillageCalculation{
+		Stats:    data.Stats,
+		RNGCheck: data.RNGCheck,
+		Terrain:  terrain,
+	}
+
+	for _, village := range data.Villages {
+		var villageSegments []*Segment
+		for _, idx := range village.Segments {
+			villageSegments = append(villageSegments, segments[idx])
+		}
+
+		level.Villages = append(level.Villages, &Village{
+			Name:            village.Name,
+			Hull:            village.Hull,
+			Segments:        villageSegments,
+			BBox:            bboxOf(village.Hull),
+			PopulationCount: village.PopulationCount,
+			FunFact:         village.FunFact,
+		})
+	}
+
+	for _, station := range data.Stations {
+		level.Stations = append(level.Stations, &Station{
+			Position: station.Position,
+			Village:  level.Villages[station.Village],
+			terrain:  terrain,
+		})
+	}
+
+	level.Mst = StationGraph{Stations: level.Stations}
+	for _, edge := range data.Mst {
+		level.Mst.Insert(StationEdge{One: level.Stations[edge[0]], Two: level.Stations[edge[1]]})
+	}
+
+	return segments, level
+}
+
+func indicesOf[T comparable](values []T) map[T]int {
+	indices := make(map[T]int, len(values))
+	for idx, value := range values {
+		indices[value] = idx
+	}
+
+	return indices
+}
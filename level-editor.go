@@ -0,0 +1,517 @@
+//go:build !headless
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	. "github.com/quasilyte/gmath"
+	"image/color"
+	"math"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+type EditorTool int
+
+const (
+	EditorToolMove EditorTool = iota
+	EditorToolAddStation
+	EditorToolDeleteStation
+	EditorToolDrawRiver
+	EditorToolEraseRiver
+)
+
+var editorToolNames = []string{"Move", "Add station", "Delete station", "Draw river", "Erase river"}
+
+// amount the budget and the population change with each click
+const editorBudgetStep = 100
+const editorPopulationStep = 100
+
+// LevelEditor lets designers change a generated level. All changes are
+// kept as LevelEdits, the level is rebuilt from them after every change.
+type LevelEditor struct {
+	// the level as it was generated
+	base VillageCalculation
+
+	Edits *LevelEdits
+
+	// the base level with the edits applied
+	Level VillageCalculation
+
+	tool EditorTool
+
+	// index of the station that is moved, -1 if none
+	dragging int
+
+	// index of the selected village, -1 if none
+	selected int
+
+	// points of the river that is drawn right now
+	river []Vec
+
+	toolButtons    []*Button
+	actionButtons  []*Button
+	villageButtons []*Button
+
+	// shown at the top, e.g. after saving
+	status string
+
+	// the text typed into the input dialog and what to do with it
+	input      string
+	inputApply func(string)
+}
+
+func (g *Game) openLevelEditor() {
+	if g.villagesAsync.Get() == nil {
+		// nothing to edit yet
+		return
+	}
+
+	_, base, ok := levelCache.Get(g.seed, g.worldSize, g.config)
+	if !ok {
+		fmt.Printf("[err] level %s is not cached, can not edit it\n", LevelId(g.seed, g.config))
+		return
+	}
+
+	edits := NewLevelEdits(g.seed, g.config, base)
+	if g.edits != nil && g.edits.Fits(g.seed, g.config) {
+		edits = g.edits.Clone()
+	}
+
+	ed := &LevelEditor{
+		base:     base,
+		Edits:    edits,
+		dragging: -1,
+		selected: -1,
+	}
+
+	for idx, name := range editorToolNames {
+		ed.toolButtons = append(ed.toolButtons, editorButton(name, func() { ed.setTool(EditorTool(idx)) }))
+	}
+
+	ed.actionButtons = []*Button{
+		editorButton("Budget +", func() { ed.changeBudget(editorBudgetStep) }),
+		editorButton("Budget -", func() { ed.changeBudget(-editorBudgetStep) }),
+		editorButton("Save", func() { ed.save(g) }),
+		editorButton("Playtest", func() { ed.playtest(g) }),
+		editorButton("Exit editor", func() { g.editor = nil }),
+	}
+
+	ed.villageButtons = []*Button{
+		editorButton("Rename", func() { ed.rename(g) }),
+		editorButton("Fun fact", func() { ed.editFunFact(g) }),
+		editorButton("Population -", func() { ed.changePopulation(-editorPopulationStep) }),
+		editorButton("Population +", func() { ed.changePopulation(editorPopulationStep) }),
+	}
+
+	ed.setTool(EditorToolMove)
+	ed.apply()
+
+	g.editor = ed
+	g.menu = nil
+	g.resetInput()
+}
+
+func editorButton(text string, onClick func()) *Button {
+	button := NewButton(text, HudButtonColors).WithAutoSize().WithOnClick(onClick)
+	button.Size.Y = 40
+	return button
+}
+
+// apply rebuilds the level from the edits
+func (ed *LevelEditor) apply() {
+	ed.Level = ed.Edits.Apply(ed.base)
+}
+
+func (ed *LevelEditor) setTool(tool EditorTool) {
+	ed.tool = tool
+	ed.river = nil
+
+	for idx, button := range ed.toolButtons {
+		button.Colors = iff(EditorTool(idx) == tool, AcceptButtonColors, HudButtonColors)
+	}
+}
+
+func (ed *LevelEditor) changeBudget(delta Coins) {
+	ed.Edits.Budget = max(0, ed.Edits.Budget+delta)
+	ed.apply()
+}
+
+func (ed *LevelEditor) changePopulation(delta int) {
+	if ed.selected < 0 {
+		return
+	}
+
+	village := &ed.Edits.Villages[ed.selected]
+	village.Population = max(0, village.Population+delta)
+	ed.apply()
+}
+
+func (ed *LevelEditor) rename(g *Game) {
+	if ed.selected < 0 {
+		return
+	}
+
+	ed.openInput(g, "Rename the village", ed.Edits.Villages[ed.selected].Name, func(name string) {
+		if name = strings.TrimSpace(name); name != "" {
+			ed.Edits.Villages[ed.selected].Name = name
+			ed.apply()
+		}
+	})
+}
+
+func (ed *LevelEditor) editFunFact(g *Game) {
+	if ed.selected < 0 {
+		return
+	}
+
+	ed.openInput(g, "Local gossip", ed.Edits.Villages[ed.selected].FunFact, func(funFact string) {
+		ed.Edits.Villages[ed.selected].FunFact = strings.TrimSpace(funFact)
+		ed.apply()
+	})
+}
+
+func (ed *LevelEditor) save(g *Game) {
+	path := fmt.Sprintf("level-%s.json", LevelId(g.seed, g.config))
+	if g.levelFile.Holds(g.seed, g.config) {
+		path = g.levelFile.Path
+	}
+
+	buf, err := MarshalLevelFile(g.seed, g.config, ed.Edits)
+	if err == nil {
+		// a download in the browser
+		err = SaveExport(path, "application/json", buf)
+	}
+
+	if err != nil {
+		fmt.Printf("[err] save level: %s\n", err)
+		ed.status = "Could not save the level: " + err.Error()
+		return
+	}
+
+	g.levelFile = &LevelFileRef{Path: path, Seed: g.seed, Config: g.config}
+	ed.status = "Saved to " + path
+}
+
+func (ed *LevelEditor) playtest(g *Game) {
+	g.resetOnUpdate = &ResetOnUpdate{
+		NextSeed:   g.seed,
+		WantSimple: g.isSimple,
+		Edits:      ed.Edits.Clone(),
+	}
+}
+
+// updateLevelEditor handles the input while the editor is open
+func (g *Game) updateLevelEditor(dt float64) {
+	ed := g.editor
+
+	g.updateCamera(dt)
+
+	var inputIntercepted bool
+
+	buttons := slices.Concat(ed.toolButtons, ed.actionButtons, iff(ed.selected >= 0, ed.villageButtons, nil))
+	for _, button := range buttons {
+		inputIntercepted = button.Hover(g.cursor) || inputIntercepted
+		button.Clicked(g.cursor)
+	}
+
+	if g.editor == nil {
+		// the editor was closed
+		return
+	}
+
+	toolKeys := []ebiten.Key{ebiten.Key1, ebiten.Key2, ebiten.Key3, ebiten.Key4, ebiten.Key5}
+	for idx, key := range toolKeys {
+		if inpututil.IsKeyJustPressed(key) {
+			ed.setTool(EditorTool(idx))
+		}
+	}
+
+	if inputIntercepted && ed.dragging < 0 && ed.river == nil {
+		return
+	}
+
+	switch ed.tool {
+	case EditorToolMove:
+		if g.cursor.JustPressed {
+			ed.dragging = ed.stationAt(g)
+			if ed.dragging < 0 {
+				ed.selected = ed.villageAt(g.cursorWorld)
+			}
+		}
+
+		if ed.dragging >= 0 {
+			// the level is rebuilt once the station is dropped
+			ed.Edits.Stations[ed.dragging].Position = g.cursorWorld
+
+			if !ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
+				ed.dragging = -1
+				ed.apply()
+			}
+		}
+
+	case EditorToolAddStation:
+		if g.cursor.JustPressed {
+			village := ed.villageAt(g.cursorWorld)
+			if village < 0 {
+				village = ed.nearestVillage(g.cursorWorld)
+			}
+
+			if village >= 0 {
+				ed.Edits.Stations = append(ed.Edits.Stations, StationEdit{Position: g.cursorWorld, Village: village})
+				ed.apply()
+			}
+		}
+
+	case EditorToolDeleteStation:
+		if idx := ed.stationAt(g); g.cursor.JustPressed && idx >= 0 {
+			ed.Edits.Stations = slices.Delete(ed.Edits.Stations, idx, idx+1)
+			ed.apply()
+		}
+
+	case EditorToolDrawRiver:
+		switch {
+		case ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft):
+			if len(ed.river) == 0 || ed.river[len(ed.river)-1].DistanceTo(g.cursorWorld) > 2*riverPointSpacing {
+				ed.river = append(ed.river, g.cursorWorld)
+			}
+
+		case len(ed.river) >= 2:
+			ed.Edits.Rivers = append(ed.Edits.Rivers, ed.river)
+			ed.river = nil
+			ed.apply()
+
+		default:
+			ed.river = nil
+		}
+
+	case EditorToolEraseRiver:
+		if idx := ed.riverAt(g.cursorWorld); g.cursor.JustPressed && idx >= 0 {
+			ed.eraseRiver(idx)
+			ed.apply()
+		}
+	}
+}
+
+// stationAt returns the index of the station below the cursor, or -1
+func (ed *LevelEditor) stationAt(g *Game) int {
+	for idx, station := range ed.Level.Stations {
+		if TransformVec(g.toScreen, station.Position).DistanceTo(g.cursorScreen) < 16 {
+			return idx
+		}
+	}
+
+	return -1
+}
+
+// villageAt returns the index of the village at the given position, or -1
+func (ed *LevelEditor) villageAt(pos Vec) int {
+	return slices.IndexFunc(ed.Level.Villages, func(village *Village) bool { return village.Contains(pos) })
+}
+
+func (ed *LevelEditor) nearestVillage(pos Vec) int {
+	nearest, nearestDistance := -1, math.Inf(1)
+
+	for idx, village := range ed.Level.Villages {
+		if distance := village.BBox.Center().DistanceTo(pos); distance < nearestDistance {
+			nearest, nearestDistance = idx, distance
+		}
+	}
+
+	return nearest
+}
+
+// riverAt returns the index of the river in the edited terrain at the given position, or -1
+func (ed *LevelEditor) riverAt(pos Vec) int {
+	for idx, river := range ed.Level.Terrain.Rivers {
+		for _, line := range river.Lines {
+			if line.DistanceToVec(pos) < drawnRiverWidth {
+				return idx
+			}
+		}
+	}
+
+	return -1
+}
+
+// eraseRiver removes a river of the edited terrain. The generated rivers
+// come first, followed by the ones drawn in the editor.
+func (ed *LevelEditor) eraseRiver(idx int) {
+	var kept []int
+	for generated := range ed.base.Terrain.Rivers {
+		if !slices.Contains(ed.Edits.RemovedRivers, generated) {
+			kept = append(kept, generated)
+		}
+	}
+
+	if idx < len(kept) {
+		ed.Edits.RemovedRivers = append(ed.Edits.RemovedRivers, kept[idx])
+		return
+	}
+
+	drawn := idx - len(kept)
+	ed.Edits.Rivers = slices.Delete(ed.Edits.Rivers, drawn, drawn+1)
+}
+
+func (ed *LevelEditor) openInput(g *Game, title, value string, apply func(string)) {
+	ed.input = value
+	ed.inputApply = apply
+
+	g.dialogStack.Push(Dialog{
+		Id:    "editor-input",
+		Modal: true,
+		Texts: []Text{{Face: Font24, Text: title, Color: DarkTextColor}},
+		Buttons: []*Button{
+			NewButton("Ok", AcceptButtonColors).WithAutoSize().WithOnClick(func() { ed.submitInput(g) }),
+			NewButton("Cancel", HudButtonColors).WithAutoSize().WithOnClick(func() {
+				g.dialogStack.CloseById("editor-input")
+			}),
+		},
+	})
+}
+
+func (ed *LevelEditor) submitInput(g *Game) {
+	g.dialogStack.CloseById("editor-input")
+	ed.inputApply(ed.input)
+}
+
+// updateEditorInput feeds the keyboard into the input dialog of the editor.
+// Returns true if the dialog is open and consumes the keyboard.
+func (g *Game) updateEditorInput() bool {
+	dialog := g.dialogStack.ById("editor-input")
+	if dialog == nil || g.editor == nil {
+		return false
+	}
+
+	ed := g.editor
+
+	for _, ch := range ebiten.AppendInputChars(nil) {
+		if len(ed.input) < 200 && unicode.IsPrint(ch) {
+			ed.input += string(ch)
+		}
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyBackspace) && len(ed.input) > 0 {
+		runes := []rune(ed.input)
+		ed.input = string(runes[:len(runes)-1])
+	}
+
+	// show the text wrapped, fun facts are long
+	dialog.Texts = dialog.Texts[:1]
+	for line := range textwrap(ed.input + "_") {
+		dialog.Texts = append(dialog.Texts, Text{Face: Font16, Text: line, Color: DarkTextColor, Offset: Vec{Y: 4}})
+	}
+
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeyEnter):
+		ed.submitInput(g)
+
+	case inpututil.IsKeyJustPressed(ebiten.KeyEscape):
+		g.dialogStack.CloseById("editor-input")
+	}
+
+	return true
+}
+
+func (g *Game) drawLevelEditor(screen *ebiten.Image) {
+	ed := g.editor
+
+	for idx, village := range ed.Level.Villages {
+		DrawVillageBounds(screen, village, DrawVillageBoundsOptions{
+			ToScreen:    g.toScreen,
+			StrokeWidth: 2,
+			StrokeColor: color.RGBA{R: 0xb0, G: 0x89, B: 0xab, A: 0x80},
+			FillColor:   color.RGBA{R: 0xb0, G: 0x89, B: 0xab, A: iff(idx == ed.selected, uint8(0x50), 0)},
+		})
+
+		pos := TransformVec(g.toScreen, village.BBox.Center())
+		DrawTextCenter(screen, village.Name, Font16, pos, DarkTextColor)
+	}
+
+	// the cheapest network shows how the budget relates to the level
+	for _, edge := range ed.Level.Mst.Edges() {
+		DrawStationConnection(screen, g.toScreen, edge.One, edge.Two, 0, true, StationColorPlanned.Stroke)
+	}
+
+	for idx, station := range ed.Level.Stations {
+		position := station.Position
+		if idx == ed.dragging {
+			position = ed.Edits.Stations[idx].Position
+		}
+
+		loc := TransformVec(g.toScreen, position)
+		DrawFillCircle(screen, loc.Add(vecSplat(2)), 10, ShadowColor)
+		DrawFillCircle(screen, loc, 10, StationColorIdle.Stroke)
+		DrawFillCircle(screen, loc, 8, StationColorIdle.Fill)
+	}
+
+	if len(ed.river) >= 2 {
+		StrokePath(screen, pathOf(ed.river, false), g.toScreen, WaterColor, &vector.StrokeOptions{
+			Width:    float32(TransformScalar(g.toScreen, drawnRiverWidth)),
+			LineJoin: vector.LineJoinRound,
+			LineCap:  vector.LineCapRound,
+		})
+	}
+
+	LayoutButtonsColumn(Vec{X: 16, Y: 16}, 8, ed.toolButtons...)
+
+	var actionsWidth float64
+	for _, button := range ed.actionButtons {
+		actionsWidth = max(actionsWidth, button.Size.X)
+	}
+
+	LayoutButtonsColumn(Vec{X: float64(g.screenWidth) - 16 - actionsWidth, Y: 16}, 8, ed.actionButtons...)
+
+	for _, button := range slices.Concat(ed.toolButtons, ed.actionButtons) {
+		button.Draw(screen)
+	}
+
+	info := fmt.Sprintf("Budget: %s, cheapest network: %s", ed.Edits.Budget, ed.Level.Mst.TotalPrice())
+	DrawTextCenter(screen, info, Font16, Vec{X: float64(g.screenWidth) / 2, Y: 24}, DarkTextColor)
+
+	if ed.status != "" {
+		DrawTextCenter(screen, ed.status, Font16, Vec{X: float64(g.screenWidth) / 2, Y: 48}, DarkTextColor)
+	}
+
+	if ed.selected >= 0 {
+		g.drawEditorVillagePanel(screen, ed.Edits.Villages[ed.selected])
+	}
+}
+
+func (g *Game) drawEditorVillagePanel(screen *ebiten.Image, village VillageEdit) {
+	dialog := Dialog{
+		Texts: []Text{
+			{Face: Font24, Text: village.Name, Color: DarkTextColor},
+			{Face: Font16, Text: fmt.Sprintf("Population: %d", village.Population), Color: DarkTextColor},
+		},
+		Buttons:     g.editor.villageButtons,
+		AlignBottom: true,
+	}
+
+	for line := range textwrap(village.FunFact) {
+		dialog.Texts = append(dialog.Texts, Text{Face: Font16, Text: line, Color: DarkTextColor})
+	}
+
+	dialog.Draw(screen)
+}
+
+// withEdits caches the generated level and applies the edits of the level editor on top of it
+func (g *Game) withEdits(villages Promise[VillageCalculation, string]) Promise[VillageCalculation, string] {
+	seed, config, generator, edits := g.seed, g.config, g.generator, g.edits
+
+	return Then(villages, func(_ context.Context, level VillageCalculation) (VillageCalculation, error) {
+		levelCache.Put(seed, config, generator, level)
+
+		if !edits.Fits(seed, config) {
+			fmt.Printf("[err] edits do not fit level %s, playing the generated level\n", LevelId(seed, config))
+			return level, nil
+		}
+
+		return edits.Apply(level), nil
+	})
+}
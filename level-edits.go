@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	. "github.com/quasilyte/gmath"
+	"hash/fnv"
+	"slices"
+)
+
+// width of the rivers drawn in the level editor at their mouth
+const drawnRiverWidth = 300.0
+
+// LevelEdits are the changes made in the level editor. They are stored
+// in the level file and applied on top of the generated level.
+type LevelEdits struct {
+	// money available to the player
+	Budget Coins `json:"budget"`
+
+	// all villages of the level, in the order they were generated in
+	Villages []VillageEdit `json:"villages"`
+
+	// all stations, they replace the generated ones
+	Stations []StationEdit `json:"stations"`
+
+	// indices of the generated rivers that were erased
+	RemovedRivers []int `json:"removedRivers,omitempty"`
+
+	// center lines of the rivers drawn in the editor, from source to mouth
+	Rivers [][]Vec `json:"rivers,omitempty"`
+
+	// the level the edits were made for, stored in the level file
+	seed             uint64
+	generatorVersion int
+	variant          string
+}
+
+type VillageEdit struct {
+	Name       string `json:"name"`
+	Population int    `json:"population"`
+	FunFact    string `json:"funFact"`
+}
+
+type StationEdit struct {
+	Position Vec `json:"position"`
+
+	// index of the village the station belongs to
+	Village int `json:"village"`
+}
+
+// NewLevelEdits describes the level as it was generated
+func NewLevelEdits(seed uint64, config GenerationConfig, level VillageCalculation) *LevelEdits {
+	edits := &LevelEdits{
+		Budget:           level.Stats.CoinsTotal,
+		seed:             seed,
+		generatorVersion: GeneratorVersion,
+		variant:          LevelVariant(config, nil),
+	}
+
+	villageIndices := indicesOf(level.Villages)
+
+	for _, village := range level.Villages {
+		edits.Villages = append(edits.Villages, VillageEdit{
+			Name:       village.Name,
+			Population: village.PopulationCount,
+			FunFact:    village.FunFact,
+		})
+	}
+
+	for _, station := range level.Stations {
+		edits.Stations = append(edits.Stations, StationEdit{
+			Position: station.Position,
+			Village:  villageIndices[station.Village],
+		})
+	}
+
+	return edits
+}
+
+// Fits checks if the edits were made for the level generated by this version
+// of the generator for the seed and config
+func (e *LevelEdits) Fits(seed uint64, config GenerationConfig) bool {
+	return e.seed == seed && e.generatorVersion == GeneratorVersion && e.variant == LevelVariant(config, nil)
+}
+
+// removeInvalidStations drops the stations of villages that do not exist.
+// Returns the number of stations dropped.
+func (e *LevelEdits) removeInvalidStations() int {
+	count := len(e.Stations)
+
+	e.Stations = slices.DeleteFunc(e.Stations, func(station StationEdit) bool {
+		return station.Village < 0 || station.Village >= len(e.Villages)
+	})
+
+	return count - len(e.Stations)
+}
+
+// Apply returns a copy of the generated level with the edits applied. The edits
+// must fit the level. The generated level and its terrain are not changed.
+func (e *LevelEdits) Apply(level VillageCalculation) VillageCalculation {
+	terrain := level.Terrain.Clone()
+
+	terrain.removeRivers(e.RemovedRivers)
+
+	for _, points := range e.Rivers {
+		terrain.addRiver(riverCourse{
+			Points: points,
+			Widths: widthsAlong(points, drawnRiverWidth*0.3, drawnRiverWidth),
+		})
+	}
+
+	// villages are shared with the level cache, never change them in place
+	var villages []*Village
+	for idx, village := range level.Villages {
+		village := *village
+
+		if idx < len(e.Villages) {
+			edit := e.Villages[idx]
+			village.Name = edit.Name
+			village.PopulationCount = edit.Population
+			village.FunFact = edit.FunFact
+		}
+
+		villages = append(villages, &village)
+	}
+
+	var stations []*Station
+	for _, edit := range e.Stations {
+		stations = append(stations, &Station{
+			Position: edit.Position,
+			Village:  villages[edit.Village],
+			terrain:  &terrain,
+		})
+	}
+
+	mst := BuildMST(StationGraph{Stations: stations})
+
+	return VillageCalculation{
+		EndTime:  level.EndTime,
+		Villages: villages,
+		Stations: stations,
+		Mst:      mst,
+		Stats: Stats{
+			CoinsTotal:    e.Budget,
+			StationsTotal: len(stations),
+		},
+		RNGCheck: level.RNGCheck,
+		Terrain:  &terrain,
+	}
+}
+
+// Hash identifies the edits, e.g. to make sure two players race on the same level
+func (e *LevelEdits) Hash() uint32 {
+	buf, _ := json.Marshal(e)
+
+	h := fnv.New32a()
+	_, _ = h.Write(buf)
+	return h.Sum32()
+}
+
+// Clone copies the edits, the copy can be changed without touching the original
+func (e *LevelEdits) Clone() *LevelEdits {
+	clone := *e
+	clone.Villages = slices.Clone(e.Villages)
+	clone.Stations = slices.Clone(e.Stations)
+	clone.RemovedRivers = slices.Clone(e.RemovedRivers)
+	clone.Rivers = slices.Clone(e.Rivers)
+	return &clone
+}
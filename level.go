@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	. "github.com/quasilyte/gmath"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// seeds of the curated levels
+var simpleLevels = []uint64{47, 49, 51, 53, 63, 68, 79}
+var hardLevels = []uint64{17, 18, 48, 35, 62, 64, 67, 88, 92}
+
+// The world has a fixed size independent of the screen,
+// so that a seed always produces the very same level.
+const worldWidth = 32000.0
+const worldHeight = 19200.0
+
+type VillageCalculation struct {
+	EndTime  time.Time
+	Villages []*Village
+	Stations []*Station
+	Mst      StationGraph
+	Stats    Stats
+	RNGCheck int
+
+	// the terrain the stations are built on
+	Terrain *Terrain
+}
+
+// LevelGenerator generates a level from its seed. All steps share the same
+// random number generator, they must always run in the very same order.
+type LevelGenerator struct {
+	rng       *rand.Rand
+	worldSize Rect
+	config    GenerationConfig
+
+	Terrain *TerrainGenerator
+	Streets StreetGenerator
+
+	// names do not use the shared rng, they are needed while the streets are generated
+	names *NameGenerator
+
+	// clusters the streets during generation, nil if villages are only collected at the end
+	tracker *VillageTracker
+}
+
+func NewLevelGenerator(seed uint64, worldSize Rect, config GenerationConfig) *LevelGenerator {
+	rng := RandWithSeed(seed)
+
+	// generate terrain
+	terrain := NewTerrainGenerator(rng, worldSize, config)
+	for range config.RiverCount {
+		terrain.GenerateRiver()
+	}
+
+	if prob(rng, config.CoastChance) {
+		terrain.GenerateCoast()
+	}
+
+	for range config.LakeCount {
+		terrain.GenerateLake()
+	}
+
+	terrain.GenerateForests()
+	terrain.GenerateContours()
+
+	// discard streets outside of the visible world
+	streets := NewStreetGenerator(rng, worldSize, terrain.Terrain(), config)
+
+	streets.StartOne(config.StartRiverDistance)
+
+	// and highways that connect the towns
+	streets.ConnectTownCenters()
+
+	return &LevelGenerator{
+		rng:       rng,
+		worldSize: worldSize,
+		config:    config,
+		Terrain:   terrain,
+		Streets:   streets,
+		names:     NewNameGenerator(RandWithSeed(seed^0x9e3779b97f4a7c15), nameThemeOf(config)),
+	}
+}
+
+// TrackVillages clusters the streets while they are generated, see PreviewVillages
+func (lg *LevelGenerator) TrackVillages() {
+	lg.tracker = NewVillageTracker(lg.config)
+}
+
+// PreviewVillages returns the villages as far as the streets are generated yet
+func (lg *LevelGenerator) PreviewVillages() []VillagePreview {
+	if lg.tracker == nil {
+		return nil
+	}
+
+	lg.tracker.Update(lg.Streets.Segments())
+	return lg.tracker.Preview(lg.names)
+}
+
+// Villages collects the villages and places the stations.
+// Must only be called after all streets have been generated.
+// Stops with the error of the context at the next step once it is cancelled.
+func (lg *LevelGenerator) Villages(ctx context.Context, yield func(string)) (VillageCalculation, error) {
+	step := func(status string) error {
+		yield(status)
+		return ctx.Err()
+	}
+
+	// find villages
+	if err := step("Collecting villages"); err != nil {
+		return VillageCalculation{}, err
+	}
+
+	var clusters [][]*Segment
+	if lg.tracker != nil {
+		// most of the work was already done during street generation
+		lg.tracker.Update(lg.Streets.Segments())
+		clusters = lg.tracker.Clusters(lg.Streets.Grid())
+	} else {
+		clusters = clusterStreets(lg.Streets.Grid(), lg.config.ClusterDistance)
+	}
+
+	villages := CollectVillages(lg.names, lg.Streets.Segments(), clusters, lg.config)
+
+	if err := step("Calculate clip rectangle"); err != nil {
+		return VillageCalculation{}, err
+	}
+
+	// do not place anything near the edge of the screen
+	clipThreshold := lg.config.ClipThreshold // m
+	clip := Rect{
+		Min: lg.worldSize.Min.Add(Vec{X: clipThreshold, Y: clipThreshold}),
+		Max: lg.worldSize.Max.Sub(Vec{X: clipThreshold, Y: clipThreshold}),
+	}
+
+	if err := step("Generating stations"); err != nil {
+		return VillageCalculation{}, err
+	}
+
+	terrain := lg.Terrain.Terrain()
+	stations := GenerateStations(lg.rng, clip, &terrain, villages)
+
+	if err := step("Calculate mst"); err != nil {
+		return VillageCalculation{}, err
+	}
+
+	mst := BuildMST(StationGraph{Stations: stations})
+
+	if err := step("Gathering local gossip"); err != nil {
+		return VillageCalculation{}, err
+	}
+
+	AssignFunFacts(RandWithSeed(lg.rng.Uint64()), lg.worldSize, terrain, villages, stations)
+
+	return VillageCalculation{
+		EndTime:  time.Now(),
+		Villages: villages,
+		Stations: stations,
+		Mst:      mst,
+		Stats: Stats{
+			// calculate the amount of money the player should have available
+			CoinsTotal:    Coins(math.Ceil(float64(mst.TotalPrice())*lg.config.BudgetFactor/10) * 10),
+			StationsTotal: len(stations),
+		},
+
+		RNGCheck: lg.rng.Int(),
+		Terrain:  &terrain,
+	}, nil
+}
+
+// GenerateLevel runs all generation steps at once, without showing any progress.
+// The generator is returned as well, it holds the streets of the level.
+func GenerateLevel(seed uint64, config GenerationConfig) (*LevelGenerator, VillageCalculation) {
+	lg := NewLevelGenerator(seed, Rect{Max: Vec{X: worldWidth, Y: worldHeight}}, config)
+
+	for lg.Streets.More() {
+		lg.Streets.Next()
+	}
+
+	// can not fail without cancellation
+	level, _ := lg.Villages(context.Background(), func(string) {})
+	return lg, level
+}
@@ -1,3 +1,5 @@
+//go:build !headless
+
 package main
 
 import (
@@ -20,7 +22,8 @@ type Loader[T any] struct {
 	playing      bool
 	game         ebiten.Game
 
-	ScreenWidth, ScreenHeight int
+	// the most recent outside size of the window
+	outsideWidth, outsideHeight int
 }
 
 func (l *Loader[T]) Update() error {
@@ -49,7 +52,7 @@ func (l *Loader[T]) Update() error {
 
 			// layout is guaranteed to be called once before
 			// the first call to Update
-			l.game.Layout(l.ScreenWidth, l.ScreenHeight)
+			l.game.Layout(l.outsideWidth, l.outsideHeight)
 
 			// and initialize the actual game
 			return l.game.Update()
@@ -71,9 +74,12 @@ func (l *Loader[T]) Draw(screen *ebiten.Image) {
 }
 
 func (l *Loader[T]) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
+	l.outsideWidth = outsideWidth
+	l.outsideHeight = outsideHeight
+
 	if l.playing {
 		return l.game.Layout(outsideWidth, outsideHeight)
 	}
 
-	return l.ScreenWidth, l.ScreenHeight
+	return deviceScaledSize(outsideWidth, outsideHeight)
 }
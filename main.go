@@ -2,69 +2,70 @@ package main
 
 import (
 	"fmt"
-	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/oliverbestmann/union-station/assets"
 	"log"
+	"os"
+	"slices"
 	"time"
 )
 
 var TimeOrigin = time.Now()
 
+// LaunchOptions are provided on the command line or as query parameters in the browser
+type LaunchOptions struct {
+	// url of the relay server to race against an opponent
+	Relay string
+
+	// lobby to join on the relay server
+	Lobby string
+
+	// seed of the level to start with
+	Seed uint64
+
+	// parameters of the level generation
+	Config GenerationConfig
+
+	// changes made to the level in the level editor
+	Edits *LevelEdits
+
+	// path of the level file the level was loaded from, if any
+	LevelFile string
+
+	// name of a bot that plays for the local player
+	Bot string
+
+	// name of the bot playing the opponents in a versus game
+	Versus string
+
+	// let bots play against each other without a window
+	Headless bool
+
+	// bots playing in a headless run
+	Bots []string
+
+	// maps written in a headless run
+	Export HeadlessExport
+}
+
+// The headless runs also build without ebiten, cgo, X11 and ALSA:
+//
+//	CGO_ENABLED=0 go build -tags headless
 func main() {
-	const windowScale = 2
-	const renderScale = 2
-
-	screenWidth, screenHeight := 800, 480
-
-	// ensure we have an audio context
-	AudioContext()
-
-	game := &Loader[Audio]{
-		ScreenWidth:  screenWidth * renderScale,
-		ScreenHeight: screenHeight * renderScale,
-
-		// load audio task
-		Promise: AsyncTask(func(yield func(string)) Audio {
-			yield("Loading audio data")
-			buttonPress := assets.ButtonPress()
-			buttonHover := assets.ButtonHover()
-			win := assets.WinSound()
-			lose := assets.LoseSound()
-
-			return Audio{
-				Songs:       assets.Songs(),
-				ButtonPress: Samples(buttonPress),
-				ButtonHover: Samples(buttonHover),
-				Win:         Samples(win),
-				Lose:        Samples(lose),
-			}
-		}),
-
-		LoadingScreen: &TheLoadingScreen{
-			now: TimeOrigin,
-		},
-
-		Next: func(audio Audio) ebiten.Game {
-			return &Game{
-				audio: audio,
-
-				screenWidth:  screenWidth * renderScale,
-				screenHeight: screenHeight * renderScale,
-			}
-		},
-	}
+	options := ParseLaunchOptions()
+
+	if options.Headless {
+		seeds := []uint64{options.Seed}
+		if options.Seed == 0 {
+			seeds = slices.Concat(simpleLevels, hardLevels)
+		}
 
-	// Specify the window size as you like. Here, a doubled size is specified.
-	ebiten.SetWindowSize(screenWidth*windowScale, screenHeight*windowScale)
-	ebiten.SetWindowTitle("Union Station")
-	ebiten.SetVsyncEnabled(true)
-	ebiten.SetTPS(ebiten.SyncWithFPS)
-	ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
+		if err := RunHeadless(os.Stdout, seeds, options.Config, options.Bots, options.Export); err != nil {
+			log.Fatal(err)
+		}
 
-	// Call ebiten.RunGame to start your game loop.
-	if err := ebiten.RunGame(game); err != nil {
-		log.Fatal(err)
+		return
 	}
+
+	runGame(options)
 }
 
 func progressYield(yield func(string), desc string) func(float64) {
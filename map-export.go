@@ -0,0 +1,180 @@
+package main
+
+import (
+	. "github.com/quasilyte/gmath"
+	"image"
+	"image/color"
+	"slices"
+)
+
+// widths and sizes in exported maps are given in pixels of a screen this wide
+// showing the whole world, the map looks just like the game zoomed out
+const mapReferenceWidth = 1600.0
+
+// size of one reference pixel in world units
+const mapPixel = worldWidth / mapReferenceWidth
+
+// MapExport is everything drawn into an exported map
+type MapExport struct {
+	// shown in the lower right corner, e.g. the level id
+	Label string
+
+	// all streets of the level, including the highways
+	Streets []*Segment
+
+	Level VillageCalculation
+
+	// the match played on the level, nil to export the level only
+	Match *Match
+}
+
+// mapCanvas is what the map is exported to. Positions and sizes are given in world units.
+type mapCanvas interface {
+	// Image stretches the image over the rectangle, interpolating between its pixels
+	Image(img image.Image, rect Rect)
+
+	// Mesh fills the triangles of a mesh, overlapping triangles are only painted once
+	Mesh(vertices []Vertex, indices []uint16, c color.Color)
+
+	Polygon(points []Vec, c color.Color)
+
+	// Lines strokes each line on its own, dashed if dash is not zero
+	Lines(lines []Line, width, dash float64, c color.Color)
+
+	Circle(center Vec, radius float64, c color.Color)
+
+	// Text writes a single line with a halo. The text is centered on pos, or ends at pos if alignRight is set.
+	Text(text string, pos Vec, size float64, alignRight bool, c color.Color)
+}
+
+// drawMap draws the layers of the map in the same order as the game does, see Terrain.Draw
+func drawMap(canvas mapCanvas, export MapExport) {
+	world := Rect{Max: Vec{X: worldWidth, Y: worldHeight}}
+
+	canvas.Polygon([]Vec{world.Min, {X: world.Max.X}, world.Max, {Y: world.Max.Y}}, BackgroundColor)
+
+	if terrain := export.Level.Terrain; terrain != nil {
+		if terrain.heights != nil {
+			canvas.Image(terrain.heights.ShadeImage())
+		}
+
+		for _, contour := range terrain.Contours {
+			canvas.Mesh(contour.Vertices, contour.Indices, ContourColor)
+		}
+
+		for _, forest := range terrain.Forests {
+			canvas.Mesh(forest.Vertices, forest.Indices, ForestColor)
+		}
+
+		canvas.Mesh(terrain.Sea.Vertices, terrain.Sea.Indices, WaterColor)
+
+		for _, lake := range terrain.Lakes {
+			canvas.Mesh(lake.Vertices, lake.Indices, WaterColor)
+		}
+
+		for _, river := range terrain.Rivers {
+			canvas.Mesh(river.Vertices, river.Indices, WaterColor)
+		}
+	}
+
+	drawMapStreets(canvas, export.Streets)
+
+	for _, village := range export.Level.Villages {
+		canvas.Polygon(village.Hull, color.NRGBA{R: 0xb0, G: 0x89, B: 0xab, A: 0x20})
+	}
+
+	if match := export.Match; match != nil {
+		drawMapConnections(canvas, match)
+	}
+
+	for _, station := range export.Level.Stations {
+		stationColor := mapStationColor(export.Match, station)
+
+		canvas.Circle(station.Position.Add(vecSplat(2*mapPixel)), 10*mapPixel, ShadowColor)
+		canvas.Circle(station.Position, 10*mapPixel, stationColor.Stroke)
+		canvas.Circle(station.Position, 8*mapPixel, stationColor.Fill)
+	}
+
+	// names go on top of everything else
+	for _, village := range export.Level.Villages {
+		canvas.Text(village.Name, village.BBox.Center(), 16*mapPixel, false, DarkTextColor)
+	}
+
+	if export.Label != "" {
+		pos := world.Max.Sub(Vec{X: 16, Y: 16}.Mulf(mapPixel))
+		canvas.Text(export.Label, pos, 12*mapPixel, true, DarkTextColor)
+	}
+}
+
+// drawMapStreets draws the streets grouped by their type, smaller streets first
+func drawMapStreets(canvas mapCanvas, streets []*Segment) {
+	var types []StreetType
+	for _, segment := range streets {
+		if !slices.Contains(types, segment.Type) {
+			types = append(types, segment.Type)
+		}
+	}
+
+	slices.SortFunc(types, func(a, b StreetType) int { return int(b) - int(a) })
+
+	for _, streetType := range types {
+		class := streetType.Class()
+		width := class.Width * mapPixel
+
+		var lines []Line
+		for _, segment := range streets {
+			if segment.Type != streetType {
+				continue
+			}
+
+			// extend the streets a little to close the gaps at the crossings
+			dir := segment.End.Sub(segment.Start).Normalized().Mulf(width / 2)
+			lines = append(lines, Line{Start: segment.Start.Sub(dir), End: segment.End.Add(dir)})
+		}
+
+		canvas.Lines(lines, width, 0, class.Color)
+	}
+}
+
+// drawMapConnections draws the planned and the constructed connections of all players
+func drawMapConnections(canvas mapCanvas, match *Match) {
+	linesOf := func(edges []StationEdge) []Line {
+		var lines []Line
+		for _, edge := range edges {
+			lines = append(lines, Line{Start: edge.One.Position, End: edge.Two.Position})
+		}
+
+		return lines
+	}
+
+	for _, player := range match.Turn.Players {
+		canvas.Lines(linesOf(player.Planning.Edges()), 2*mapPixel, 10*mapPixel, StationColorPlanned.Stroke)
+	}
+
+	for _, player := range match.Turn.Players {
+		canvas.Lines(linesOf(player.Graph.Edges()), 4*mapPixel, 10*mapPixel, player.Color.Stroke)
+	}
+}
+
+// mapStationColor colors a station like the game does when nothing is selected
+func mapStationColor(match *Match, station *Station) StationColor {
+	if match == nil {
+		return StationColorIdle
+	}
+
+	if match.Accepted.HasConnections(station) {
+		if owner := match.OwnerOf(station); owner != nil {
+			return owner.Color
+		}
+
+		return StationColorConstructed
+	}
+
+	for _, player := range match.Turn.Players {
+		if player.Planning.HasConnections(station) {
+			return StationColorPlanned
+		}
+	}
+
+	return StationColorIdle
+}
@@ -0,0 +1,193 @@
+package main
+
+import "time"
+
+// Match holds the state all players of a level share and
+// decides which actions a player is allowed to take.
+type Match struct {
+	// all connections constructed by any player
+	Accepted StationGraph
+
+	Turn TurnController
+}
+
+type ActionKind uint8
+
+const (
+	ActionBuild ActionKind = iota
+	ActionPlan
+	ActionUnplan
+)
+
+// Action is a single move a player makes in the game
+type Action struct {
+	Kind ActionKind
+	One  *Station
+	Two  *Station
+}
+
+// Start prepares all players for a level with the given stations and budget.
+// In a multiplayer match the budget is split between all players.
+func (m *Match) Start(stations []*Station, stats Stats) {
+	m.Accepted.Stations = stations
+
+	if m.Turn.IsMultiplayer() {
+		stats.CoinsTotal = hotSeatBudget(stats.CoinsTotal, len(m.Turn.Players))
+	}
+
+	for _, player := range m.Turn.Players {
+		player.Start(stations, stats)
+	}
+}
+
+// OwnerOf returns the player that has claimed the station by
+// connecting it first, or nil, if the station is still unclaimed.
+func (m *Match) OwnerOf(station *Station) *Player {
+	for _, player := range m.Turn.Players {
+		if player.Graph.HasConnections(station) {
+			return player
+		}
+	}
+
+	return nil
+}
+
+// CanBuild checks if the player is allowed to construct a connection between both stations
+func (m *Match) CanBuild(player *Player, one, two *Station) bool {
+	if one == nil || two == nil || one == two {
+		return false
+	}
+
+	if m.Accepted.Has(one, two) {
+		return false
+	}
+
+	if player.Stats.CoinsAvailable() < priceOf(one, two) {
+		return false
+	}
+
+	// stations claimed by other players are off-limits
+	for _, station := range []*Station{one, two} {
+		if owner := m.OwnerOf(station); owner != nil && owner != player {
+			return false
+		}
+	}
+
+	return true
+}
+
+// CanAct checks if the player is still able to construct any connection
+func (m *Match) CanAct(player *Player) bool {
+	stations := m.Accepted.Stations
+
+	for idx, one := range stations {
+		for _, two := range stations[idx+1:] {
+			if m.CanBuild(player, one, two) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Apply applies the action for the given player. Returns false, if the action is not valid.
+func (m *Match) Apply(player *Player, action Action, now time.Time) bool {
+	one, two := action.One, action.Two
+
+	switch action.Kind {
+	case ActionBuild:
+		if !m.CanBuild(player, one, two) {
+			return false
+		}
+
+		scoreUpdate := buildScore(&player.Graph, one, two)
+
+		edge := StationEdge{
+			One:     one,
+			Two:     two,
+			Created: now,
+		}
+
+		// accept the station
+		player.Graph.Insert(edge)
+		m.Accepted.Insert(edge)
+
+		// and remove it from planning, if it is still in there
+		player.Planning.Remove(one, two)
+
+		// update the score based on the number of stations already connected and the number
+		// of newly connected peopled
+		player.Stats.Score += scoreUpdate
+		player.Stats.StationsConnected = player.Graph.ConnectedCount()
+
+	case ActionPlan:
+		if one == nil || two == nil || one == two {
+			return false
+		}
+
+		player.Planning.Insert(StationEdge{
+			Created: now,
+			One:     one,
+			Two:     two,
+		})
+
+	case ActionUnplan:
+		player.Planning.Remove(one, two)
+
+	default:
+		return false
+	}
+
+	// update the amount of money spend
+	player.Stats.CoinsSpent = player.Graph.TotalPrice()
+	player.Stats.CoinsPlanned = player.Planning.TotalPrice()
+
+	return true
+}
+
+// Clone creates a deep copy of the match, e.g. to try out actions
+func (m *Match) Clone() *Match {
+	clone := &Match{
+		Accepted: m.Accepted.Clone(),
+		Turn:     TurnController{Current: m.Turn.Current},
+	}
+
+	for _, player := range m.Turn.Players {
+		playerClone := *player
+		playerClone.Graph = player.Graph.Clone()
+		playerClone.Planning = player.Planning.Clone()
+
+		clone.Turn.Players = append(clone.Turn.Players, &playerClone)
+	}
+
+	return clone
+}
+
+// buildScore calculates the score a player receives for constructing a
+// connection between both stations in addition to the given graph.
+func buildScore(graph *StationGraph, one, two *Station) int {
+	var newlyConnectedCount int
+
+	if !graph.VillageIsConnected(one.Village) {
+		newlyConnectedCount += one.Village.PopulationCount
+	}
+
+	if !graph.VillageIsConnected(two.Village) {
+		if one.Village != two.Village {
+			newlyConnectedCount += two.Village.PopulationCount
+		}
+	}
+
+	// count the number of stations connected after construction
+	stationsConnected := graph.ConnectedCount()
+
+	for _, station := range []*Station{one, two} {
+		if !graph.HasConnections(station) {
+			stationsConnected += 1
+		}
+	}
+
+	stationCount := len(graph.Stations)
+	return (stationCount - (stationsConnected - 1)) * newlyConnectedCount / stationCount
+}
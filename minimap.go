@@ -0,0 +1,175 @@
+//go:build !headless
+
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	. "github.com/quasilyte/gmath"
+	"image/color"
+)
+
+type Minimap struct {
+	Hidden bool
+
+	// position of the minimap on the screen
+	Rect Rect
+
+	// transforms world coordinates into the minimaps local coordinates
+	toMinimap ebiten.GeoM
+
+	// static layers like rivers and villages, only rendered when invalidated
+	background *ebiten.Image
+	dirty      bool
+
+	dragging bool
+}
+
+func (m *Minimap) Layout(screenSize Vec, worldSize Rect) {
+	width := min(screenSize.X*0.2, 400)
+	height := width * worldSize.Height() / worldSize.Width()
+
+	// place it in the bottom left corner of the screen
+	m.Rect = Rect{
+		Min: Vec{X: 16, Y: screenSize.Y - 16 - height},
+		Max: Vec{X: 16 + width, Y: screenSize.Y - 16},
+	}
+
+	scale := width / worldSize.Width()
+
+	m.toMinimap = ebiten.GeoM{}
+	m.toMinimap.Translate(-worldSize.Min.X, -worldSize.Min.Y)
+	m.toMinimap.Scale(scale, scale)
+
+	m.Invalidate()
+}
+
+func (m *Minimap) Invalidate() {
+	m.dirty = true
+}
+
+func (m *Minimap) Toggle() {
+	m.Hidden = !m.Hidden
+	m.dragging = false
+}
+
+func (m *Minimap) Hover(cursor CursorState) bool {
+	return !m.Hidden && (m.dragging || m.Rect.Contains(cursor.Position))
+}
+
+// Update returns the world position the player has picked on the minimap, if any.
+func (m *Minimap) Update(cursor CursorState) (Vec, bool) {
+	if m.Hidden {
+		return Vec{}, false
+	}
+
+	if cursor.JustPressed && m.Rect.Contains(cursor.Position) {
+		m.dragging = true
+	}
+
+	if !m.dragging {
+		return Vec{}, false
+	}
+
+	if cursor.JustReleased {
+		m.dragging = false
+	}
+
+	toWorld := m.toMinimap
+	toWorld.Invert()
+
+	local := cursor.Position.Sub(m.Rect.Min)
+	return TransformVec(toWorld, local), true
+}
+
+func (g *Game) drawMinimap(screen *ebiten.Image) {
+	m := &g.minimap
+	if m.Hidden {
+		return
+	}
+
+	size := m.Rect.Size()
+
+	if m.background == nil || imageWidth(m.background) != int(size.X) || imageHeight(m.background) != int(size.Y) {
+		if m.background != nil {
+			m.background.Deallocate()
+		}
+
+		m.background = ebiten.NewImage(int(size.X), int(size.Y))
+		m.dirty = true
+	}
+
+	result := g.villagesAsync.Get()
+
+	if m.dirty {
+		m.dirty = false
+
+		m.background.Fill(BackgroundColor)
+
+		g.terrain.Draw(m.background, m.toMinimap)
+
+		if result != nil {
+			for _, village := range result.Villages {
+				DrawVillageBounds(m.background, village, DrawVillageBoundsOptions{
+					ToScreen:    m.toMinimap,
+					FillColor:   color.RGBA{R: 0x97, G: 0x8c, B: 0x63, A: 0x60},
+					StrokeWidth: 1,
+					StrokeColor: rgbaOf(0x978c63ff),
+				})
+			}
+		}
+	}
+
+	DrawWindow(screen, m.Rect.Min.Sub(vecSplat(4)), size.Add(vecSplat(8)))
+
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(m.Rect.Min.X, m.Rect.Min.Y)
+	screen.DrawImage(m.background, op)
+
+	// transform from world to the minimap on the screen
+	toScreen := m.toMinimap
+	toScreen.Translate(m.Rect.Min.X, m.Rect.Min.Y)
+
+	drawEdge := func(edge StationEdge, width float32, c color.Color) {
+		start := TransformVec(toScreen, edge.One.Position)
+		end := TransformVec(toScreen, edge.Two.Position)
+		vector.StrokeLine(screen, float32(start.X), float32(start.Y), float32(end.X), float32(end.Y), width, c, true)
+	}
+
+	for _, edge := range g.player().Planning.Edges() {
+		drawEdge(edge, 1, StationColorPlanned.Stroke)
+	}
+
+	for _, player := range g.match.Turn.Players {
+		for _, edge := range player.Graph.Edges() {
+			drawEdge(edge, 2, player.Color.Stroke)
+		}
+	}
+
+	if result != nil {
+		for _, station := range result.Stations {
+			stationColor, _ := g.stationColorOf(station)
+
+			pos := TransformVec(toScreen, station.Position)
+			DrawFillCircle(screen, pos, 4, stationColor.Stroke)
+			DrawFillCircle(screen, pos, 3, stationColor.Fill)
+		}
+	}
+
+	// indicate the part of the world that is currently visible
+	viewport := Rect{
+		Min: TransformVec(toScreen, TransformVec(g.toWorld, Vec{})),
+		Max: TransformVec(toScreen, TransformVec(g.toWorld, Vec{X: float64(g.screenWidth), Y: float64(g.screenHeight)})),
+	}
+
+	viewport.Min.X = max(viewport.Min.X, m.Rect.Min.X)
+	viewport.Min.Y = max(viewport.Min.Y, m.Rect.Min.Y)
+	viewport.Max.X = min(viewport.Max.X, m.Rect.Max.X)
+	viewport.Max.Y = min(viewport.Max.Y, m.Rect.Max.Y)
+
+	vector.StrokeRect(screen,
+		float32(viewport.Min.X), float32(viewport.Min.Y),
+		float32(viewport.Width()), float32(viewport.Height()),
+		2, StationColorSelected.Stroke, true,
+	)
+}
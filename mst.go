@@ -45,23 +45,28 @@ func BuildMST(graph StationGraph) StationGraph {
 	return mst
 }
 
-type UnionFind struct {
-	parent map[*Station]*Station
+type UnionFind[T comparable] struct {
+	parent map[T]T
 }
 
-func NewUnionFind(stations []*Station) *UnionFind {
-	uf := &UnionFind{
-		parent: map[*Station]*Station{},
+func NewUnionFind[T comparable](items []T) *UnionFind[T] {
+	uf := &UnionFind[T]{
+		parent: map[T]T{},
 	}
 
-	for _, station := range stations {
-		uf.parent[station] = station
+	for _, item := range items {
+		uf.parent[item] = item
 	}
 
 	return uf
 }
 
-func (uf *UnionFind) Find(x *Station) *Station {
+// Add inserts a new item as its own set
+func (uf *UnionFind[T]) Add(item T) {
+	uf.parent[item] = item
+}
+
+func (uf *UnionFind[T]) Find(x T) T {
 	root := x
 	for uf.parent[root] != root {
 		root = uf.parent[root]
@@ -76,7 +81,7 @@ func (uf *UnionFind) Find(x *Station) *Station {
 	return root
 }
 
-func (uf *UnionFind) Union(x, y *Station) bool {
+func (uf *UnionFind[T]) Union(x, y T) bool {
 	rootX := uf.Find(x)
 	rootY := uf.Find(y)
 
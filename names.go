@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"github.com/oliverbestmann/union-station/assets"
+	"math"
+	"math/rand/v2"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// NameTheme is parsed from a data file in assets/names. The names train
+// the markov chain, prefixes and suffixes are added to some generated names.
+type NameTheme struct {
+	Names    []string
+	Prefixes []string
+	Suffixes []string
+}
+
+// ParseNameTheme parses a data file with the sections [names], [prefixes]
+// and [suffixes], one entry per line. Lines starting with # are ignored.
+func ParseNameTheme(data string) (NameTheme, error) {
+	var theme NameTheme
+	var section *[]string
+
+	for lineNo, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		switch line {
+		case "[names]":
+			section = &theme.Names
+		case "[prefixes]":
+			section = &theme.Prefixes
+		case "[suffixes]":
+			section = &theme.Suffixes
+
+		default:
+			if section == nil {
+				return NameTheme{}, fmt.Errorf("line %d: entry outside of a section", lineNo+1)
+			}
+
+			*section = append(*section, line)
+		}
+	}
+
+	if len(theme.Names) == 0 {
+		return NameTheme{}, fmt.Errorf("theme has no names")
+	}
+
+	return theme, nil
+}
+
+// NameThemeByName loads one of the themes embedded in the assets
+func NameThemeByName(name string) (NameTheme, error) {
+	data, ok := assets.NameTheme(name)
+	if !ok {
+		return NameTheme{}, fmt.Errorf("unknown name theme %q, expected one of %v", name, assets.NameThemes())
+	}
+
+	return ParseNameTheme(data)
+}
+
+// order of the markov chain, the number of letters that decide the next letter
+const nameChainOrder = 3
+
+// chance that a generated name gets a prefix or suffix
+const nameAffixChance = 0.15
+
+// NameGenerator generates village names from a markov chain trained on
+// the names of a theme. Never returns the same name twice.
+type NameGenerator struct {
+	rng   *rand.Rand
+	theme NameTheme
+
+	// names generated for a key are derived from this seed
+	seed uint64
+
+	// letters following the previous letters. Letters are repeated
+	// according to their frequency in the training names.
+	chain map[string][]rune
+
+	used Set[string]
+}
+
+func NewNameGenerator(rng *rand.Rand, theme NameTheme) *NameGenerator {
+	ng := &NameGenerator{
+		rng:   rng,
+		theme: theme,
+		seed:  rng.Uint64(),
+		chain: map[string][]rune{},
+	}
+
+	for _, name := range theme.Names {
+		// ^ marks the start, $ the end of the name
+		runes := []rune(strings.Repeat("^", nameChainOrder) + strings.ToLower(name) + "$")
+
+		for idx := nameChainOrder; idx < len(runes); idx++ {
+			key := string(runes[idx-nameChainOrder : idx])
+			ng.chain[key] = append(ng.chain[key], runes[idx])
+		}
+	}
+
+	return ng
+}
+
+// Next generates the next name
+func (ng *NameGenerator) Next() string {
+	for attempt := 0; ; attempt++ {
+		name := ng.candidate()
+
+		// add affixes, more of them once we run out of plain names
+		if prob(ng.rng, nameAffixChance) || attempt > 50 {
+			name = ng.withAffix(name)
+		}
+
+		if attempt > 1000 {
+			// the theme is exhausted, count up to stay unique
+			name = fmt.Sprintf("%s %d", name, attempt-1000+2)
+		}
+
+		if !ng.used.Has(name) {
+			ng.used.Insert(name)
+			return name
+		}
+	}
+}
+
+// NameFor generates the name for the given key. The name does not depend on the
+// names generated before, unless it is already taken.
+func (ng *NameGenerator) NameFor(key uint64) string {
+	ng.rng = RandWithSeed(ng.seed ^ key)
+	return ng.Next()
+}
+
+// PeekFor returns the name NameFor would return for the key if no name was taken yet.
+// The name is not marked as used.
+func (ng *NameGenerator) PeekFor(key uint64) string {
+	used := ng.used
+	defer func() { ng.used = used }()
+
+	ng.used = Set[string]{}
+	return ng.NameFor(key)
+}
+
+func (ng *NameGenerator) candidate() string {
+	var best string
+	bestMiss := math.MaxInt
+
+	for attempt := 0; attempt <= 1000; attempt++ {
+		name := ng.walk()
+
+		// skip names that are too short or too long to read well
+		length := utf8.RuneCountInString(name)
+		miss := max(5-length, length-12, 0)
+		if miss == 0 {
+			return name
+		}
+
+		if miss < bestMiss {
+			best, bestMiss = name, miss
+		}
+	}
+
+	// the theme does not produce names of a readable length, take the closest one
+	return best
+}
+
+func (ng *NameGenerator) walk() string {
+	var name []rune
+
+	key := strings.Repeat("^", nameChainOrder)
+
+	for {
+		next := Choose(ng.rng, ng.chain[key]...)
+		if next == '$' || len(name) > 20 {
+			break
+		}
+
+		name = append(name, next)
+
+		keyRunes := []rune(key)
+		key = string(append(keyRunes[1:], next))
+	}
+
+	if len(name) > 0 {
+		name[0] = unicode.ToUpper(name[0])
+	}
+
+	return string(name)
+}
+
+func (ng *NameGenerator) withAffix(name string) string {
+	usePrefix := len(ng.theme.Suffixes) == 0 || len(ng.theme.Prefixes) > 0 && prob(ng.rng, 0.3)
+
+	switch {
+	case usePrefix && len(ng.theme.Prefixes) > 0:
+		prefix := Choose(ng.rng, ng.theme.Prefixes...)
+		if strings.HasSuffix(prefix, "-") {
+			return prefix + name
+		}
+
+		return prefix + " " + name
+
+	case len(ng.theme.Suffixes) > 0:
+		suffix := Choose(ng.rng, ng.theme.Suffixes...)
+		if strings.HasPrefix(suffix, "-") {
+			return name + suffix
+		}
+
+		return name + " " + suffix
+
+	default:
+		return name
+	}
+}
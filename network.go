@@ -0,0 +1,386 @@
+//go:build !headless
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/oliverbestmann/union-station/relay"
+	. "github.com/quasilyte/gmath"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NetworkSession is a connection to a lobby on the relay server
+type NetworkSession struct {
+	conn *websocket.Conn
+
+	// level both players race on
+	Seed uint64
+
+	// index of the local player, as assigned by the relay
+	LocalPlayer int
+
+	outgoing chan relay.Message
+
+	mu       sync.Mutex
+	incoming []relay.Message
+	closed   bool
+
+	// why the session broke, reported once by ErrOnce
+	err     error
+	errSeen bool
+}
+
+// ConnectRelay joins the lobby on the relay and waits for an opponent. The level
+// variant must match the one of the opponent, see LevelVariant.
+// Cancelling the promise leaves the lobby.
+func ConnectRelay(relayUrl, lobby string, seed uint64, level string) Promise[*NetworkSession, string] {
+	return AsyncTaskContext(context.Background(), func(ctx context.Context, yield func(string)) (*NetworkSession, error) {
+		yield("Connecting to relay")
+
+		session, err := dialRelay(ctx, relayUrl, lobby, seed, level)
+		if err == nil && ctx.Err() != nil {
+			// left the lobby just as the opponent joined, the promise drops the session
+			session.Close()
+		}
+
+		return session, err
+	})
+}
+
+func dialRelay(ctx context.Context, relayUrl, lobby string, seed uint64, level string) (*NetworkSession, error) {
+	uri := strings.TrimRight(relayUrl, "/") + "/lobby/" + url.PathEscape(lobby)
+
+	dialCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	conn, _, err := websocket.Dial(dialCtx, uri, nil)
+	if err != nil {
+		return nil, fmt.Errorf("connect to relay: %w", err)
+	}
+
+	if err := wsjson.Write(ctx, conn, relay.Message{Type: relay.TypeHello, Seed: seed, Level: level}); err != nil {
+		_ = conn.CloseNow()
+		return nil, fmt.Errorf("send hello: %w", err)
+	}
+
+	// wait until the lobby is full
+	var start relay.Message
+	if err := wsjson.Read(ctx, conn, &start); err != nil {
+		_ = conn.CloseNow()
+		return nil, fmt.Errorf("wait for opponent: %w", err)
+	}
+
+	if start.Type != relay.TypeStart {
+		_ = conn.CloseNow()
+		return nil, fmt.Errorf("unexpected message %q", start.Type)
+	}
+
+	// the actions refer to stations by index, both players must play the very same level
+	if start.Level != level || (seed != 0 && start.Seed != seed) {
+		_ = conn.CloseNow()
+		return nil, fmt.Errorf("opponent plays level %d-%s, expected %d-%s", start.Seed, start.Level, seed, level)
+	}
+
+	session := &NetworkSession{
+		conn:        conn,
+		Seed:        start.Seed,
+		LocalPlayer: start.Player,
+		outgoing:    make(chan relay.Message, 16),
+	}
+
+	go session.readLoop()
+	go session.writeLoop()
+
+	return session, nil
+}
+
+func (s *NetworkSession) readLoop() {
+	for {
+		var msg relay.Message
+		if err := wsjson.Read(context.Background(), s.conn, &msg); err != nil {
+			// treat a broken connection like an opponent that has left
+			msg = relay.Message{Type: relay.TypeLeft}
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			// closed locally, nobody is interested in the rest
+			s.mu.Unlock()
+			return
+		}
+
+		s.incoming = append(s.incoming, msg)
+		closed := msg.Type == relay.TypeLeft
+		s.mu.Unlock()
+
+		if closed {
+			return
+		}
+	}
+}
+
+func (s *NetworkSession) writeLoop() {
+	for msg := range s.outgoing {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err := wsjson.Write(ctx, s.conn, msg)
+		cancel()
+
+		if err != nil {
+			// the action would never be echoed back, the race can not go on
+			s.fail(fmt.Errorf("send to relay: %w", err))
+			return
+		}
+	}
+}
+
+// Poll returns all messages received since the last call
+func (s *NetworkSession) Poll() []relay.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	messages := s.incoming
+	s.incoming = nil
+	return messages
+}
+
+func (s *NetworkSession) Send(msg relay.Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+
+	// never block the game loop on a slow connection
+	select {
+	case s.outgoing <- msg:
+	default:
+		s.err = errors.New("the relay does not keep up with your moves")
+		s.closeLocked()
+	}
+}
+
+// ErrOnce returns the error that broke the session, only the first time it is available
+func (s *NetworkSession) ErrOnce() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err == nil || s.errSeen {
+		return nil
+	}
+
+	s.errSeen = true
+	return s.err
+}
+
+// fail closes the session, the error is reported by ErrOnce
+func (s *NetworkSession) fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err == nil && !s.closed {
+		s.err = err
+	}
+
+	s.closeLocked()
+}
+
+func (s *NetworkSession) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closeLocked()
+}
+
+func (s *NetworkSession) closeLocked() {
+	if s.closed {
+		return
+	}
+
+	s.closed = true
+	close(s.outgoing)
+
+	go func() { _ = s.conn.Close(websocket.StatusNormalClosure, "") }()
+}
+
+// connectRelay starts to join a lobby, the level starts once an opponent has joined
+func (g *Game) connectRelay(relayUrl, lobby string, seed uint64) {
+	g.lobby = lobby
+	g.connecting = ConnectRelay(relayUrl, lobby, seed, LevelVariant(g.config, g.edits))
+}
+
+func (g *Game) updateNetwork() {
+	if g.connecting.Waiting() && g.dialogStack.ById("relay-lobby") == nil {
+		g.dialogStack.Push(Dialog{
+			Id:    "relay-lobby",
+			Modal: true,
+			Texts: []Text{
+				{
+					Face:  Font24,
+					Text:  "Waiting on the platform...",
+					Color: DarkTextColor,
+				},
+				{
+					Face:   Font16,
+					Text:   fmt.Sprintf("Your opponent can join you in lobby %q.", g.lobby),
+					Color:  DarkTextColor,
+					Offset: Vec{Y: 8},
+				},
+			},
+			Buttons: []*Button{
+				NewButton("Leave", HudButtonColors).WithAutoSize().WithOnClick(g.connecting.Cancel),
+			},
+		})
+	}
+
+	if err := g.connecting.ErrOnce(); err != nil {
+		g.dialogStack.CloseById("relay-lobby")
+
+		if errors.Is(err, context.Canceled) {
+			// the player has left the lobby and plays alone
+			return
+		}
+
+		g.showNetworkError(err.Error())
+		return
+	}
+
+	if result := g.connecting.GetOnce(); result != nil {
+		g.dialogStack.CloseById("relay-lobby")
+
+		session := *result
+
+		g.resetOnUpdate = &ResetOnUpdate{
+			NextSeed: session.Seed,
+			Players:  relay.PlayerCount,
+			Network:  session,
+
+			// the opponent plays with the same edits
+			Edits: g.edits,
+		}
+
+		return
+	}
+
+	// wait until the stations are known before applying any action
+	if g.net == nil || len(g.match.Accepted.Stations) == 0 {
+		return
+	}
+
+	if err := g.net.ErrOnce(); err != nil && !g.won {
+		g.showNetworkError(err.Error())
+		return
+	}
+
+	for _, msg := range g.net.Poll() {
+		switch msg.Type {
+		case relay.TypeAction:
+			if msg.Player < 0 || msg.Player >= len(g.match.Turn.Players) {
+				continue
+			}
+
+			action, ok := g.decodeAction(msg.Action)
+			if !ok {
+				continue
+			}
+
+			// the relay decides the order, actions that are no
+			// longer valid are ignored by all players alike
+			g.match.Apply(g.match.Turn.Players[msg.Player], action, g.now)
+
+			g.checkRaceFinished()
+
+		case relay.TypeLeft:
+			g.net.Close()
+
+			if !g.won {
+				g.showNetworkError("Your opponent has left the station.")
+			}
+		}
+	}
+}
+
+// sendAction sends the action to the relay. It is applied once the relay sends it back.
+func (g *Game) sendAction(action Action) {
+	g.net.Send(relay.Message{
+		Type: relay.TypeAction,
+		Action: &relay.Action{
+			Kind: uint8(action.Kind),
+			One:  slices.Index(g.match.Accepted.Stations, action.One),
+			Two:  slices.Index(g.match.Accepted.Stations, action.Two),
+		},
+	})
+}
+
+func (g *Game) decodeAction(action *relay.Action) (Action, bool) {
+	stations := g.match.Accepted.Stations
+
+	if action == nil {
+		return Action{}, false
+	}
+
+	if action.One < 0 || action.One >= len(stations) || action.Two < 0 || action.Two >= len(stations) {
+		return Action{}, false
+	}
+
+	return Action{
+		Kind: ActionKind(action.Kind),
+		One:  stations[action.One],
+		Two:  stations[action.Two],
+	}, true
+}
+
+// checkRaceFinished ends the race once no player can construct anything anymore
+func (g *Game) checkRaceFinished() {
+	if g.won {
+		return
+	}
+
+	for _, player := range g.match.Turn.Players {
+		if g.match.CanAct(player) {
+			return
+		}
+	}
+
+	g.won = true
+
+	g.audio.Play(g.audio.Win)
+
+	g.dialogStack.Push(g.hotSeatResultDialog())
+}
+
+func (g *Game) showNetworkError(message string) {
+	g.dialogStack.Push(Dialog{
+		Id:    "network-error",
+		Modal: true,
+		Texts: []Text{
+			{
+				Face:  Font24,
+				Text:  "The line has gone dead",
+				Color: DarkTextColor,
+			},
+			{
+				Face:   Font16,
+				Text:   message,
+				Color:  DarkTextColor,
+				Offset: Vec{Y: 8},
+			},
+		},
+		Buttons: []*Button{
+			NewButton("Play alone", AcceptButtonColors).WithAutoSize().WithOnClick(func() {
+				g.resetOnUpdate = &ResetOnUpdate{
+					NextSeed: g.seed,
+					Players:  1,
+				}
+			}),
+		},
+	})
+}
@@ -3,7 +3,16 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"github.com/oliverbestmann/union-station/assets"
 	"github.com/pkg/profile"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
 )
 
 var Debug = true
@@ -22,3 +31,125 @@ func (i *IdleSuspend) MaybeSuspend() {
 func PlayerName() string {
 	return "Hopfenherrscher"
 }
+
+func ParseLaunchOptions() LaunchOptions {
+	var opts LaunchOptions
+
+	flag.StringVar(&opts.Relay, "relay", "", "url of the relay server for a networked race, e.g. ws://localhost:8080")
+	flag.StringVar(&opts.Lobby, "lobby", "default", "name of the lobby to join on the relay server")
+	flag.Uint64Var(&opts.Seed, "seed", 0, "seed of the level to play")
+	preset := flag.String("preset", "classic", "preset of the level generation, one of "+strings.Join(GenerationPresetNames(), ", "))
+	levelFile := flag.String("level", "", "json file with the seed and generation config of the level to play")
+	nameTheme := flag.String("names", "", "theme of the village names, one of "+strings.Join(assets.NameThemes(), ", "))
+	flag.StringVar(&opts.Bot, "bot", "", "let a bot play for you, one of "+strings.Join(AgentNames, ", "))
+	flag.StringVar(&opts.Versus, "versus", defaultVersusBot, "bot playing your opponents in a versus game, one of "+strings.Join(AgentNames, ", "))
+	flag.BoolVar(&opts.Headless, "headless", false, "let bots play the curated levels (or -seed) without a window and print the results")
+	flag.StringVar(&opts.Export.SvgDir, "svg", "", "directory to export a svg map of each level played in a headless run to")
+	flag.StringVar(&opts.Export.PngDir, "png", "", "directory to export a png map of each level played in a headless run to")
+	flag.IntVar(&opts.Export.PngWidth, "png-width", 1600, "width of the png maps in pixels")
+	bots := flag.String("bots", strings.Join(AgentNames, ","), "comma separated list of bots playing in a headless run")
+	flag.Parse()
+
+	opts.Bots = strings.Split(*bots, ",")
+
+	config, ok := GenerationPresetByName(*preset)
+	if !ok {
+		log.Fatalf("unknown preset %q, expected one of %v", *preset, GenerationPresetNames())
+	}
+
+	opts.Config = config
+
+	if *levelFile != "" {
+		seed, config, edits, err := LoadLevelFile(*levelFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// the level file wins over -seed and -preset
+		opts.Seed, opts.Config, opts.Edits = seed, config, edits
+
+		// the level editor saves back to the same file
+		opts.LevelFile = *levelFile
+	}
+
+	if *nameTheme != "" {
+		if _, err := NameThemeByName(*nameTheme); err != nil {
+			log.Fatal(err)
+		}
+
+		opts.Config.NameTheme = *nameTheme
+	}
+
+	return opts
+}
+
+func levelCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "union-station", "levels"), nil
+}
+
+func readCachedLevel(key string) ([]byte, bool) {
+	dir, err := levelCacheDir()
+	if err != nil {
+		return nil, false
+	}
+
+	buf, err := os.ReadFile(filepath.Join(dir, key+".json"))
+	return buf, err == nil
+}
+
+func writeCachedLevel(key string, buf []byte) {
+	dir, err := levelCacheDir()
+	if err != nil {
+		fmt.Printf("[err] no cache directory: %s\n", err)
+		return
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		fmt.Printf("[err] create cache directory: %s\n", err)
+		return
+	}
+
+	// levels of other generator versions will never be read again
+	stale, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	for _, path := range stale {
+		if !strings.HasSuffix(path, fmt.Sprintf("-v%d.json", GeneratorVersion)) {
+			_ = os.Remove(path)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, key+".json"), buf, 0o644); err != nil {
+		fmt.Printf("[err] write cached level: %s\n", err)
+	}
+}
+
+// SaveExport writes an exported file to the working directory
+func SaveExport(name string, mimeType string, buf []byte) error {
+	if err := os.WriteFile(name, buf, 0o644); err != nil {
+		return err
+	}
+
+	fmt.Printf("Exported %s\n", name)
+	return nil
+}
+
+// CopyToClipboard uses the clipboard tool of the platform and calls done once it has finished
+func CopyToClipboard(text string, done func(err error)) {
+	var cmd *exec.Cmd
+
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("pbcopy")
+	case "windows":
+		cmd = exec.Command("clip")
+	default:
+		cmd = exec.Command("xclip", "-selection", "clipboard")
+	}
+
+	cmd.Stdin = strings.NewReader(text)
+	done(cmd.Run())
+}
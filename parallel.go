@@ -0,0 +1,43 @@
+package main
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// parallelMap calls fn for each item on a few worker go-routines. The results are
+// in the order of the items, so the outcome does not depend on the scheduling.
+// Each worker has its own IdleSuspend to keep the browser responsive.
+func parallelMap[T any, R any](items []T, fn func(idle *IdleSuspend, item T) R) []R {
+	results := make([]R, len(items))
+
+	workers := min(runtime.GOMAXPROCS(0), len(items))
+
+	var wg sync.WaitGroup
+	var next atomic.Int64
+
+	for range workers {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			var idle IdleSuspend
+
+			for {
+				// take the next item
+				idx := int(next.Add(1) - 1)
+				if idx >= len(items) {
+					return
+				}
+
+				results[idx] = fn(&idle, items[idx])
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return results
+}
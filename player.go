@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+type Player struct {
+	Name  string
+	Color StationColor
+
+	// the connections this player has constructed
+	Graph StationGraph
+
+	// the connections this player has planned
+	Planning StationGraph
+
+	Stats Stats
+
+	// the bot playing for this player, nil for humans
+	Agent Agent
+}
+
+func NewPlayers(count int) []*Player {
+	var players []*Player
+
+	for idx := range count {
+		players = append(players, &Player{
+			Name:  fmt.Sprintf("Player %d", idx+1),
+			Color: PlayerColors[idx%len(PlayerColors)],
+		})
+	}
+
+	return players
+}
+
+// Start prepares the player for a level with the given stations and budget
+func (p *Player) Start(stations []*Station, stats Stats) {
+	p.Graph.Stations = stations
+	p.Planning.Stations = stations
+	p.Stats = stats
+}
+
+// TurnController decides which player is allowed to build next
+type TurnController struct {
+	Players []*Player
+	Current int
+}
+
+func (t *TurnController) Active() *Player {
+	return t.Players[t.Current]
+}
+
+func (t *TurnController) IsMultiplayer() bool {
+	return len(t.Players) > 1
+}
+
+// Advance passes the turn to the next player that can still act.
+// Returns false if no player is able to act anymore.
+func (t *TurnController) Advance(canAct func(player *Player) bool) bool {
+	for offset := 1; offset <= len(t.Players); offset++ {
+		idx := (t.Current + offset) % len(t.Players)
+
+		if canAct(t.Players[idx]) {
+			t.Current = idx
+			return true
+		}
+	}
+
+	return false
+}
+
+// hotSeatBudget splits the total budget evenly between all players
+func hotSeatBudget(total Coins, playerCount int) Coins {
+	share := float64(total) / float64(playerCount)
+	return Coins(math.Ceil(share/10) * 10)
+}
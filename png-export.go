@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"github.com/oliverbestmann/union-station/assets"
+	. "github.com/quasilyte/gmath"
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/f64"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"sync"
+)
+
+// pngCanvas rasterizes the map in software, it does not need ebiten or a gpu
+type pngCanvas struct {
+	img    *image.RGBA
+	raster *vector.Rasterizer
+
+	// pixels per world unit
+	scale float64
+
+	// font faces by their size in pixels
+	faces map[float64]font.Face
+}
+
+var mapFont = sync.OnceValue(func() *opentype.Font {
+	f, _ := opentype.Parse(assets.FontData())
+	return f
+})
+
+// RenderPng rasterizes the map into a png image that is width pixels wide.
+// It is used for thumbnails and golden images on machines without a gpu.
+func RenderPng(export MapExport, width int) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, RasterizeMap(export, width)); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// RasterizeMap draws the map into an image that is width pixels wide
+func RasterizeMap(export MapExport, width int) *image.RGBA {
+	scale := float64(width) / worldWidth
+	height := int(math.Round(worldHeight * scale))
+
+	canvas := &pngCanvas{
+		img:    image.NewRGBA(image.Rect(0, 0, width, height)),
+		raster: vector.NewRasterizer(width, height),
+		scale:  scale,
+		faces:  map[float64]font.Face{},
+	}
+
+	drawMap(canvas, export)
+
+	return canvas.img
+}
+
+// path adds a closed polygon to the rasterizer. All polygons are added with the same
+// winding, the rasterizer would cut out overlapping polygons of opposite winding.
+func (c *pngCanvas) path(points ...Vec) {
+	var area float64
+	for idx, point := range points {
+		next := points[(idx+1)%len(points)]
+		area += point.X*next.Y - next.X*point.Y
+	}
+
+	for idx := range points {
+		point := points[idx]
+		if area < 0 {
+			point = points[len(points)-1-idx]
+		}
+
+		x, y := float32(point.X*c.scale), float32(point.Y*c.scale)
+		if idx == 0 {
+			c.raster.MoveTo(x, y)
+		} else {
+			c.raster.LineTo(x, y)
+		}
+	}
+
+	c.raster.ClosePath()
+}
+
+// fill paints all polygons added since the last fill
+func (c *pngCanvas) fill(fill color.Color) {
+	bounds := c.img.Bounds()
+	c.raster.Draw(c.img, bounds, image.NewUniform(fill), image.Point{})
+	c.raster.Reset(bounds.Dx(), bounds.Dy())
+}
+
+func (c *pngCanvas) Image(img image.Image, rect Rect) {
+	bounds := img.Bounds()
+
+	// maps the pixels of the image onto the canvas
+	sx := rect.Width() * c.scale / float64(bounds.Dx())
+	sy := rect.Height() * c.scale / float64(bounds.Dy())
+	transform := f64.Aff3{sx, 0, rect.Min.X * c.scale, 0, sy, rect.Min.Y * c.scale}
+
+	draw.BiLinear.Transform(c.img, transform, img, bounds, draw.Over, nil)
+}
+
+func (c *pngCanvas) Mesh(vertices []Vertex, indices []uint16, fill color.Color) {
+	if len(indices) == 0 {
+		return
+	}
+
+	vecOf := func(vertex Vertex) Vec {
+		return Vec{X: float64(vertex.DstX), Y: float64(vertex.DstY)}
+	}
+
+	for idx := 0; idx+2 < len(indices); idx += 3 {
+		c.path(vecOf(vertices[indices[idx]]), vecOf(vertices[indices[idx+1]]), vecOf(vertices[indices[idx+2]]))
+	}
+
+	c.fill(fill)
+}
+
+func (c *pngCanvas) Polygon(points []Vec, fill color.Color) {
+	if len(points) < 3 {
+		return
+	}
+
+	c.path(points...)
+	c.fill(fill)
+}
+
+func (c *pngCanvas) Lines(lines []Line, width, dash float64, stroke color.Color) {
+	if len(lines) == 0 {
+		return
+	}
+
+	quad := func(start, end Vec) {
+		dir := end.Sub(start).Normalized()
+		normal := Vec{X: -dir.Y, Y: dir.X}.Mulf(width / 2)
+		c.path(start.Add(normal), end.Add(normal), end.Sub(normal), start.Sub(normal))
+	}
+
+	for _, line := range lines {
+		if dash <= 0 {
+			quad(line.Start, line.End)
+			continue
+		}
+
+		length := line.Start.DistanceTo(line.End)
+		dir := line.End.Sub(line.Start).Normalized()
+
+		for f := 0.0; f < length; f += 2 * dash {
+			quad(line.Start.Add(dir.Mulf(f)), line.Start.Add(dir.Mulf(min(f+dash, length))))
+		}
+	}
+
+	c.fill(stroke)
+}
+
+func (c *pngCanvas) Circle(center Vec, radius float64, fill color.Color) {
+	const steps = 32
+
+	points := make([]Vec, steps)
+	for idx := range points {
+		angle := Rad(2 * math.Pi * float64(idx) / steps)
+		points[idx] = center.Add(RadToVec(angle).Mulf(radius))
+	}
+
+	c.path(points...)
+	c.fill(fill)
+}
+
+func (c *pngCanvas) Text(text string, pos Vec, size float64, alignRight bool, fill color.Color) {
+	face := c.face(size * c.scale)
+	if face == nil {
+		return
+	}
+
+	drawer := font.Drawer{Dst: c.img, Face: face}
+
+	// anchor the text at the center of its capital letters
+	x := pos.X*c.scale - float64(drawer.MeasureString(text))/64*iff(alignRight, 1.0, 0.5)
+	y := pos.Y*c.scale + float64(face.Metrics().CapHeight)/64/2
+
+	drawAt := func(x, y float64) {
+		drawer.Dot = fixed.Point26_6{X: fixed.Int26_6(x * 64), Y: fixed.Int26_6(y * 64)}
+		drawer.DrawString(text)
+	}
+
+	// the halo keeps the text readable on top of the streets
+	halo := max(1, 1.5*mapPixel*c.scale)
+
+	drawer.Src = image.NewUniform(BackgroundColor)
+	for idx := range 8 {
+		offset := RadToVec(Rad(math.Pi / 4 * float64(idx))).Mulf(halo)
+		drawAt(x+offset.X, y+offset.Y)
+	}
+
+	drawer.Src = image.NewUniform(fill)
+	drawAt(x, y)
+}
+
+func (c *pngCanvas) face(size float64) font.Face {
+	if face, ok := c.faces[size]; ok {
+		return face
+	}
+
+	f := mapFont()
+	if f == nil {
+		return nil
+	}
+
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{Size: size, DPI: 72})
+	if err != nil {
+		return nil
+	}
+
+	c.faces[size] = face
+	return face
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	. "github.com/quasilyte/gmath"
+	"math"
+	"slices"
 	"sort"
 )
 
@@ -48,21 +50,127 @@ func ConvexHull(points []Vec) []Vec {
 	return hull
 }
 
-// PointInConvexHull checks whether point p is inside the convex hull defined by hull.
-// The hull should be ordered counter-clockwise and be a closed or open loop (first == last optional).
-func PointInConvexHull(hull []Vec, p Vec) bool {
-	n := len(hull)
-	if n < 3 {
-		return false // Not a polygon
+// PointInTriangle checks whether point p is inside the triangle a, b, c, independent of the winding order
+func PointInTriangle(a, b, c, p Vec) bool {
+	d1 := cross3(a, b, p)
+	d2 := cross3(b, c, p)
+	d3 := cross3(c, a, p)
+
+	hasNegative := d1 < 0 || d2 < 0 || d3 < 0
+	hasPositive := d1 > 0 || d2 > 0 || d3 > 0
+
+	return !(hasNegative && hasPositive)
+}
+
+// ConcaveHull returns a concave hull of the points by digging into the edges of the convex hull.
+// Edges longer than maxEdgeLength are split at the nearest point inside the hull, as long as the
+// hull stays a simple polygon. The result is in counter-clockwise order.
+func ConcaveHull(points []Vec, maxEdgeLength float64) []Vec {
+	hull := ConvexHull(points)
+	if len(hull) < 3 {
+		return hull
+	}
+
+	var onHull Set[Vec]
+	for _, point := range hull {
+		onHull.Insert(point)
+	}
+
+	// points that might still be added to the hull, without duplicates
+	var inner []Vec
+	for _, point := range points {
+		if !onHull.Has(point) {
+			onHull.Insert(point)
+			inner = append(inner, point)
+		}
+	}
+
+	for idx := 0; idx < len(hull); {
+		a, b := hull[idx], hull[(idx+1)%len(hull)]
+
+		edge := Line{Start: a, End: b}
+		if edge.Length() <= maxEdgeLength {
+			idx++
+			continue
+		}
+
+		candidateIdx, ok := digCandidate(hull, idx, inner)
+		if !ok {
+			idx++
+			continue
+		}
+
+		// split the edge at the candidate, the new edge from a
+		// to the candidate is checked again in the next iteration
+		hull = slices.Insert(hull, idx+1, inner[candidateIdx])
+		inner = slices.Delete(inner, candidateIdx, candidateIdx+1)
+	}
+
+	return hull
+}
+
+// digCandidate finds the inner point nearest to the edge starting at hull[idx]
+// that can be added to the hull without creating an intersection
+func digCandidate(hull []Vec, idx int, inner []Vec) (int, bool) {
+	a, b := hull[idx], hull[(idx+1)%len(hull)]
+	edge := Line{Start: a, End: b}
+	edgeLength := edge.Length()
+	ab := b.Sub(a)
+
+	candidateIdx, candidateDistance := -1, math.Inf(1)
+
+	for pointIdx, point := range inner {
+		// only points in front of the edge
+		t := point.Sub(a).Dot(ab) / ab.LenSquared()
+		if t <= 0 || t >= 1 {
+			continue
+		}
+
+		// both new edges must be shorter, or we would never stop digging
+		if point.DistanceTo(a) >= edgeLength || point.DistanceTo(b) >= edgeLength {
+			continue
+		}
+
+		if distance := edge.DistanceToVec(point); distance < candidateDistance {
+			candidateIdx, candidateDistance = pointIdx, distance
+		}
+	}
+
+	if candidateIdx < 0 {
+		return 0, false
 	}
 
-	for i := 0; i < n; i++ {
-		a := hull[i]
-		b := hull[(i+1)%n]
-		if cross3(a, b, p) < 0 {
-			// Point is to the right of edge a->b, i.e., outside the hull
-			return false
+	point := inner[candidateIdx]
+	first, second := Line{Start: a, End: point}, Line{Start: point, End: b}
+
+	for otherIdx := range hull {
+		other := Line{Start: hull[otherIdx], End: hull[(otherIdx+1)%len(hull)]}
+
+		// skip the edge itself and its neighbours, they share a point with the new edges
+		if otherIdx == idx || otherIdx == (idx+1)%len(hull) || (otherIdx+1)%len(hull) == idx {
+			continue
+		}
+
+		if other.Intersects(first) || other.Intersects(second) {
+			return 0, false
+		}
+	}
+
+	return candidateIdx, true
+}
+
+// PointInPolygon checks whether point p is inside the simple polygon using ray casting.
+// The polygon does not need to be convex.
+func PointInPolygon(polygon []Vec, p Vec) bool {
+	var inside bool
+
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		a, b := polygon[i], polygon[j]
+
+		if (a.Y > p.Y) != (b.Y > p.Y) && p.X < (b.X-a.X)*(p.Y-a.Y)/(b.Y-a.Y)+a.X {
+			inside = !inside
 		}
 	}
-	return true
+
+	return inside
 }
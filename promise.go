@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// ErrTimeout is the error of a promise that did not finish in time
+var ErrTimeout = errors.New("timeout")
+
+// Promise is the result of a task running in the background. The zero value
+// is a promise that was never started. P is the type of progress updates.
+type Promise[T any, P any] struct {
+	state *promiseState[T, P]
+}
+
+type promiseState[T any, P any] struct {
+	result   atomic.Pointer[T]
+	err      atomic.Pointer[error]
+	progress atomic.Pointer[P]
+	seen     atomic.Bool
+
+	// closed once the task has finished
+	done chan struct{}
+
+	cancel context.CancelFunc
+}
+
+// AsyncTask runs the task in a new go-routine. The task can not be cancelled.
+func AsyncTask[T any, P any](task func(yield func(P)) T) Promise[T, P] {
+	return AsyncTaskContext(context.Background(), func(ctx context.Context, yield func(P)) (T, error) {
+		return task(yield), nil
+	})
+}
+
+// AsyncTaskContext runs the task in a new go-routine. The context passed to the
+// task is cancelled with the promise, the task should check it regularly and
+// stop early with the error of the context.
+func AsyncTaskContext[T any, P any](ctx context.Context, task func(ctx context.Context, yield func(P)) (T, error)) Promise[T, P] {
+	ctx, cancel := context.WithCancel(ctx)
+
+	state := &promiseState[T, P]{
+		done:   make(chan struct{}),
+		cancel: cancel,
+	}
+
+	// spawn go-routine with task
+	go func() {
+		defer cancel()
+		defer close(state.done)
+
+		value, err := task(ctx, func(p P) {
+			state.progress.Store(&p)
+		})
+
+		if err == nil {
+			// a task might have finished even though it was cancelled at the last moment
+			err = context.Cause(ctx)
+		}
+
+		if err != nil {
+			state.err.Store(&err)
+			return
+		}
+
+		state.result.Store(&value)
+	}()
+
+	return Promise[T, P]{state: state}
+}
+
+// Get returns the result once the task has finished successfully
+func (p Promise[T, P]) Get() *T {
+	if p.state == nil {
+		return nil
+	}
+
+	return p.state.result.Load()
+}
+
+// GetOnce returns the result only the first time it is available
+func (p Promise[T, P]) GetOnce() *T {
+	value := p.Get()
+	if value == nil || !p.state.seen.CompareAndSwap(false, true) {
+		return nil
+	}
+
+	return value
+}
+
+// Err returns the error once the task has failed or was cancelled
+func (p Promise[T, P]) Err() error {
+	if p.state == nil {
+		return nil
+	}
+
+	if err := p.state.err.Load(); err != nil {
+		return *err
+	}
+
+	return nil
+}
+
+// ErrOnce returns the error only the first time it is available
+func (p Promise[T, P]) ErrOnce() error {
+	err := p.Err()
+	if err == nil || !p.state.seen.CompareAndSwap(false, true) {
+		return nil
+	}
+
+	return err
+}
+
+// Status returns the most recent progress of a running task
+func (p Promise[T, P]) Status() *P {
+	if p.state == nil || p.Done() {
+		return nil
+	}
+
+	return p.state.progress.Load()
+}
+
+func (p Promise[T, P]) Started() bool {
+	return p.state != nil
+}
+
+// Done reports if the task has finished, either successfully or with an error
+func (p Promise[T, P]) Done() bool {
+	if p.state == nil {
+		return false
+	}
+
+	select {
+	case <-p.state.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p Promise[T, P]) Waiting() bool {
+	return p.Started() && !p.Done()
+}
+
+// Cancel asks the task to stop. Does nothing if the task has already finished.
+func (p Promise[T, P]) Cancel() {
+	if p.state != nil {
+		p.state.cancel()
+	}
+}
+
+// Await blocks until the task has finished or the context is cancelled
+func (p Promise[T, P]) Await(ctx context.Context) (T, error) {
+	var zero T
+
+	if p.state == nil {
+		return zero, errors.New("promise was never started")
+	}
+
+	select {
+	case <-p.state.done:
+	case <-ctx.Done():
+		return zero, context.Cause(ctx)
+	}
+
+	if err := p.Err(); err != nil {
+		return zero, err
+	}
+
+	return *p.Get(), nil
+}
+
+// Then runs fn with the result of the promise once it has finished successfully.
+// Errors are passed on without calling fn. Cancelling the returned promise
+// also cancels the original one.
+func Then[T any, U any, P any](p Promise[T, P], fn func(ctx context.Context, value T) (U, error)) Promise[U, P] {
+	return AsyncTaskContext(context.Background(), func(ctx context.Context, yield func(P)) (U, error) {
+		stop := context.AfterFunc(ctx, p.Cancel)
+		defer stop()
+
+		value, err := p.Await(ctx)
+		if err != nil {
+			var zero U
+			return zero, err
+		}
+
+		return fn(ctx, value)
+	})
+}
+
+// All waits for all promises to finish successfully. The first error
+// cancels all other promises and fails the returned promise.
+func All[T any, P any](promises ...Promise[T, P]) Promise[[]T, P] {
+	cancelAll := func() {
+		for _, p := range promises {
+			p.Cancel()
+		}
+	}
+
+	return AsyncTaskContext(context.Background(), func(ctx context.Context, yield func(P)) ([]T, error) {
+		stop := context.AfterFunc(ctx, cancelAll)
+		defer stop()
+
+		values := make([]T, len(promises))
+
+		for idx, p := range promises {
+			value, err := p.Await(ctx)
+			if err != nil {
+				cancelAll()
+				return nil, err
+			}
+
+			values[idx] = value
+		}
+
+		return values, nil
+	})
+}
+
+// Timeout fails with ErrTimeout and cancels the promise if it does not finish in time
+func Timeout[T any, P any](p Promise[T, P], timeout time.Duration) Promise[T, P] {
+	return AsyncTaskContext(context.Background(), func(ctx context.Context, yield func(P)) (T, error) {
+		ctx, cancel := context.WithTimeoutCause(ctx, timeout, ErrTimeout)
+		defer cancel()
+
+		stop := context.AfterFunc(ctx, p.Cancel)
+		defer stop()
+
+		return p.Await(ctx)
+	})
+}
@@ -0,0 +1,54 @@
+// Package relay contains the protocol spoken between the game and the relay server.
+//
+// Both players of a lobby play the same deterministic level, identified by its seed
+// and the variant of the level, which covers the generation config and any edits.
+// Only the actions of the players travel over the wire. The relay assigns a sequence
+// number to each action and sends it to all players, including the one that sent it.
+// Every client applies the actions in the very same order, which keeps the game
+// state identical on both sides.
+package relay
+
+// PlayerCount is the number of players that race against each other in one lobby
+const PlayerCount = 2
+
+const (
+	// TypeHello is sent by a client after connecting to a lobby
+	TypeHello = "hello"
+
+	// TypeStart is sent by the relay once the lobby is full
+	TypeStart = "start"
+
+	// TypeAction is sent by a client and forwarded to all clients by the relay
+	TypeAction = "action"
+
+	// TypeLeft is sent by the relay if a player has left the lobby
+	TypeLeft = "left"
+)
+
+type Message struct {
+	Type string `json:"type"`
+
+	// seed of the level. Optionally requested by the first player
+	// in the hello message, decided by the relay in the start message.
+	Seed uint64 `json:"seed,omitempty"`
+
+	// identifies the generation config and the edits of the level. Sent in the
+	// hello message, the relay only pairs players that generate the same level.
+	Level string `json:"level,omitempty"`
+
+	// index of the player, assigned by the relay
+	Player int `json:"player"`
+
+	// sequence number of an action, assigned by the relay
+	Seq int `json:"seq,omitempty"`
+
+	Action *Action `json:"action,omitempty"`
+}
+
+type Action struct {
+	Kind uint8 `json:"kind"`
+
+	// stations are identified by their index in the list of generated stations
+	One int `json:"one"`
+	Two int `json:"two"`
+}
@@ -0,0 +1,218 @@
+//go:build !headless
+
+package main
+
+import (
+	"fmt"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	. "github.com/quasilyte/gmath"
+	"time"
+)
+
+// LevelResults summarizes the network of a player at the end of a level
+// and compares it to the optimal network
+type LevelResults struct {
+	// total length of all tracks in meters
+	TrackLength float64
+
+	CoinsSpent   Coins
+	CoinsOptimal Coins
+
+	// true if the network of the player connects all stations
+	Complete bool
+
+	LongestEdge StationEdge
+
+	// connections that were planned but never built
+	PlannedUnbuilt int
+
+	TimeTaken time.Duration
+
+	// connections built by the player that are not part of the optimal network
+	ExtraEdges []StationEdge
+
+	// connections of the optimal network the player has not built
+	MissingEdges []StationEdge
+}
+
+func ComputeLevelResults(player *Player, optimal StationGraph, timeTaken time.Duration) LevelResults {
+	results := LevelResults{
+		CoinsSpent:     player.Graph.TotalPrice(),
+		CoinsOptimal:   optimal.TotalPrice(),
+		Complete:       player.Graph.IsConnected(),
+		PlannedUnbuilt: len(player.Planning.Edges()),
+		TimeTaken:      timeTaken,
+	}
+
+	for _, edge := range player.Graph.Edges() {
+		length := edge.Length()
+		results.TrackLength += length
+
+		if results.LongestEdge.One == nil || length > results.LongestEdge.Length() {
+			results.LongestEdge = edge
+		}
+
+		if !optimal.Has(edge.One, edge.Two) {
+			results.ExtraEdges = append(results.ExtraEdges, edge)
+		}
+	}
+
+	for _, edge := range optimal.Edges() {
+		if !player.Graph.Has(edge.One, edge.Two) {
+			results.MissingEdges = append(results.MissingEdges, edge)
+		}
+	}
+
+	return results
+}
+
+// Efficiency is the price of the optimal network relative to the money spent
+func (r *LevelResults) Efficiency() float64 {
+	if r.CoinsSpent == 0 {
+		return 0
+	}
+
+	return float64(r.CoinsOptimal) / float64(r.CoinsSpent)
+}
+
+// finishLevel records the results of the active player once the level has ended
+func (g *Game) finishLevel() {
+	level := g.villagesAsync.Get()
+	if level == nil {
+		return
+	}
+
+	results := ComputeLevelResults(g.player(), level.Mst, g.now.Sub(g.playStartTime))
+	g.results = &results
+}
+
+func (g *Game) showResults() {
+	r := g.results
+	if r == nil {
+		return
+	}
+
+	texts := []Text{
+		{
+			Face:  Font24,
+			Text:  "The engineer's report",
+			Color: DarkTextColor,
+		},
+		{
+			Face:   Font16,
+			Text:   "Here's how your network measures up against the finest one money can buy.",
+			Color:  DarkTextColor,
+			Offset: Vec{Y: 8},
+		},
+	}
+
+	availableWidth := MeasureTexts(texts).X
+
+	efficiency := "network incomplete"
+	if r.Complete {
+		efficiency = fmt.Sprintf("%d%%", int(r.Efficiency()*100))
+	}
+
+	longestEdge := "none"
+	if r.LongestEdge.One != nil {
+		longestEdge = fmt.Sprintf("%.1f km, %s to %s",
+			r.LongestEdge.Length()/1000,
+			r.LongestEdge.One.Village.Name,
+			r.LongestEdge.Two.Village.Name,
+		)
+	}
+
+	rows := [][2]string{
+		{"Track laid", fmt.Sprintf("%.1f km", r.TrackLength/1000)},
+		{"Money spent", fmt.Sprintf("%s (optimum %s)", r.CoinsSpent, r.CoinsOptimal)},
+		{"Efficiency", efficiency},
+		{"Longest stretch", longestEdge},
+		{"Planned, never built", fmt.Sprintf("%d", r.PlannedUnbuilt)},
+		{"Time taken", formatCountdown(r.TimeTaken)},
+	}
+
+	for idx, row := range rows {
+		yOffset := iff(idx == 0, 8.0, 0)
+
+		texts = append(texts, Text{
+			Face:   Font16,
+			Text:   row[0],
+			Color:  DarkTextColor,
+			Height: new(float64),
+			Offset: Vec{Y: yOffset},
+		})
+
+		// manually right align with availableWidth
+		x := availableWidth - MeasureText(Font16, row[1]).X
+
+		texts = append(texts, Text{
+			Face:   Font16,
+			Text:   row[1],
+			Color:  DarkTextColor,
+			Offset: Vec{X: x},
+		})
+	}
+
+	g.dialogStack.Push(Dialog{
+		Id:    "results",
+		Modal: true,
+		Texts: texts,
+		Buttons: []*Button{
+			NewButton("Compare on map", AcceptButtonColors).WithAutoSize().WithOnClick(func() {
+				g.dialogStack.CloseById("results")
+				g.showComparison()
+			}),
+
+			NewButton("Back", HudButtonColors).WithAutoSize().WithOnClick(func() {
+				g.dialogStack.CloseById("results")
+			}),
+		},
+	})
+}
+
+// showComparison draws the network of the player on top of the optimal network
+func (g *Game) showComparison() {
+	g.comparison = true
+
+	r := g.results
+
+	g.dialogStack.Push(Dialog{
+		Id:          "comparison",
+		Modal:       true,
+		AlignBottom: true,
+		Texts: []Text{
+			{
+				Face:  Font16,
+				Text:  fmt.Sprintf("Red: %d of your connections the optimal network does without.", len(r.ExtraEdges)),
+				Color: ComparisonExtraColor,
+			},
+			{
+				Face:  Font16,
+				Text:  fmt.Sprintf("Dashed: %d connections of the optimal network you have not built.", len(r.MissingEdges)),
+				Color: ComparisonMissingColor,
+			},
+		},
+		Buttons: []*Button{
+			NewButton("Back to the report", HudButtonColors).WithAutoSize().WithOnClick(func() {
+				g.comparison = false
+				g.dialogStack.CloseById("comparison")
+				g.showResults()
+			}),
+		},
+	})
+}
+
+func (g *Game) drawComparison(screen *ebiten.Image) {
+	r := g.results
+
+	for _, edge := range r.MissingEdges {
+		DrawStationConnection(screen, g.toScreen, edge.One, edge.Two, 0, true, ComparisonMissingColor)
+	}
+
+	for _, edge := range r.ExtraEdges {
+		start := TransformVec(g.toScreen, edge.One.Position)
+		end := TransformVec(g.toScreen, edge.Two.Position)
+		vector.StrokeLine(screen, float32(start.X), float32(start.Y), float32(end.X), float32(end.Y), 6, ComparisonExtraColor, true)
+	}
+}
@@ -0,0 +1,307 @@
+//go:build !headless
+
+package main
+
+import (
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/oliverbestmann/union-station/assets"
+	. "github.com/quasilyte/gmath"
+	"hash/fnv"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// ShareCode identifies a level together with the way it was played. It
+// is short enough to be typed in by hand.
+type ShareCode struct {
+	Seed uint64
+
+	// name of the generation preset and the village names
+	Preset    string
+	NameTheme string
+
+	TimeAttack bool
+	Simple     bool
+
+	// number of players taking turns, the last ones played by bots
+	Players int
+	Bots    int
+}
+
+// version of the encoding, increase when the layout changes
+const shareCodeVersion = 1
+
+// crockford base32 avoids letters that look like digits
+var shareCodeEncoding = base32.NewEncoding("0123456789ABCDEFGHJKMNPQRSTVWXYZ").WithPadding(base32.NoPadding)
+
+var ErrInvalidShareCode = errors.New("invalid share code")
+
+// String encodes the share code in groups of four characters
+func (sc ShareCode) String() string {
+	presetIdx := slices.IndexFunc(GenerationPresets, func(preset GenerationPreset) bool { return preset.Name == sc.Preset })
+	themeIdx := slices.Index(assets.NameThemes(), sc.NameTheme)
+
+	buf := []byte{shareCodeVersion}
+	buf = binary.AppendUvarint(buf, sc.Seed)
+	buf = append(buf,
+		byte(max(presetIdx, 0)),
+		byte(max(themeIdx, 0)),
+		iff(sc.TimeAttack, byte(1), 0)|iff(sc.Simple, byte(2), 0),
+		byte(sc.Players<<4|sc.Bots&0xf),
+	)
+
+	// catches most typos
+	buf = append(buf, shareCodeChecksum(buf))
+
+	encoded := shareCodeEncoding.EncodeToString(buf)
+
+	// groups of four are easier to read out loud
+	var groups []string
+	for len(encoded) > 4 {
+		groups = append(groups, encoded[:4])
+		encoded = encoded[4:]
+	}
+
+	return strings.Join(append(groups, encoded), "-")
+}
+
+// ParseShareCode decodes a share code. The code is not case-sensitive,
+// dashes and whitespace are ignored.
+func ParseShareCode(code string) (ShareCode, error) {
+	code = strings.Map(func(ch rune) rune {
+		switch ch {
+		case '-', ' ', '\t':
+			return -1
+		case 'O':
+			return '0'
+		case 'I', 'L':
+			return '1'
+		default:
+			return ch
+		}
+	}, strings.ToUpper(code))
+
+	buf, err := shareCodeEncoding.DecodeString(code)
+	if err != nil || len(buf) < 2 {
+		return ShareCode{}, ErrInvalidShareCode
+	}
+
+	payload, checksum := buf[:len(buf)-1], buf[len(buf)-1]
+	if shareCodeChecksum(payload) != checksum {
+		return ShareCode{}, fmt.Errorf("%w: checksum does not match", ErrInvalidShareCode)
+	}
+
+	if payload[0] != shareCodeVersion {
+		return ShareCode{}, fmt.Errorf("%w: unknown version %d", ErrInvalidShareCode, payload[0])
+	}
+
+	seed, n := binary.Uvarint(payload[1:])
+	if n <= 0 || len(payload) != 1+n+4 {
+		return ShareCode{}, ErrInvalidShareCode
+	}
+
+	params := payload[1+n:]
+
+	presetIdx, themeIdx := int(params[0]), int(params[1])
+	if presetIdx >= len(GenerationPresets) {
+		return ShareCode{}, fmt.Errorf("%w: unknown preset", ErrInvalidShareCode)
+	}
+
+	themes := assets.NameThemes()
+	if themeIdx >= len(themes) {
+		return ShareCode{}, fmt.Errorf("%w: unknown name theme", ErrInvalidShareCode)
+	}
+
+	sc := ShareCode{
+		Seed:       seed,
+		Preset:     GenerationPresets[presetIdx].Name,
+		NameTheme:  themes[themeIdx],
+		TimeAttack: params[2]&1 != 0,
+		Simple:     params[2]&2 != 0,
+		Players:    int(params[3] >> 4),
+		Bots:       int(params[3] & 0xf),
+	}
+
+	if sc.Players < 1 || sc.Players > len(PlayerColors) || sc.Bots >= sc.Players {
+		return ShareCode{}, fmt.Errorf("%w: invalid number of players", ErrInvalidShareCode)
+	}
+
+	return sc, nil
+}
+
+func shareCodeChecksum(buf []byte) byte {
+	h := fnv.New32a()
+	_, _ = h.Write(buf)
+	return byte(h.Sum32())
+}
+
+// Config returns the generation config of the shared level
+func (sc ShareCode) Config() GenerationConfig {
+	config, _ := GenerationPresetByName(sc.Preset)
+	config.NameTheme = sc.NameTheme
+	return config
+}
+
+// shareCode describes the current level. Levels generated with a config that is
+// not one of the presets, e.g. from a level file, or edited levels can not be shared.
+func (g *Game) shareCode() (ShareCode, bool) {
+	if g.edits != nil {
+		// edits only live in the level file
+		return ShareCode{}, false
+	}
+
+	for _, preset := range GenerationPresets {
+		config := g.config
+		config.NameTheme = preset.Config.NameTheme
+
+		if config != preset.Config {
+			continue
+		}
+
+		return ShareCode{
+			Seed:       g.seed,
+			Preset:     preset.Name,
+			NameTheme:  g.config.NameTheme,
+			TimeAttack: g.timeAttack,
+			Simple:     g.isSimple,
+			Players:    len(g.match.Turn.Players),
+			Bots:       g.bots,
+		}, true
+	}
+
+	return ShareCode{}, false
+}
+
+// longest code accepted in the input dialog
+const shareCodeMaxLength = 32
+
+func (g *Game) showShareCodeDialog() {
+	g.shareCodeInput = ""
+
+	g.dialogStack.Push(Dialog{
+		Id:    "share-code",
+		Modal: true,
+		Texts: []Text{
+			{
+				Face:  Font24,
+				Text:  "Enter a level code",
+				Color: DarkTextColor,
+			},
+			{
+				Face:   Font16,
+				Text:   "Someone shared a map with you? Type in their code to play the very same level.",
+				Color:  DarkTextColor,
+				Offset: Vec{Y: 8},
+			},
+			{
+				Face:   Font24,
+				Text:   "_",
+				Color:  DarkTextColor,
+				Offset: Vec{Y: 16},
+			},
+			{
+				// room for an error message
+				Face:   Font16,
+				Text:   " ",
+				Color:  DarkTextColor,
+				Offset: Vec{Y: 8},
+			},
+		},
+		Buttons: []*Button{
+			NewButton("Play", AcceptButtonColors).WithAutoSize().WithOnClick(g.submitShareCode),
+			NewButton("Cancel", HudButtonColors).WithAutoSize().WithOnClick(func() {
+				g.dialogStack.CloseById("share-code")
+			}),
+		},
+	})
+}
+
+// updateShareCodeInput feeds the keyboard into the share code dialog.
+// Returns true if the dialog is open and consumes the keyboard.
+func (g *Game) updateShareCodeInput() bool {
+	dialog := g.dialogStack.ById("share-code")
+	if dialog == nil {
+		return false
+	}
+
+	for _, ch := range ebiten.AppendInputChars(nil) {
+		if len(g.shareCodeInput) < shareCodeMaxLength && (unicode.IsLetter(ch) || unicode.IsDigit(ch) || ch == '-') {
+			g.shareCodeInput += strings.ToUpper(string(ch))
+		}
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyBackspace) && len(g.shareCodeInput) > 0 {
+		g.shareCodeInput = g.shareCodeInput[:len(g.shareCodeInput)-1]
+	}
+
+	dialog.Texts[2].Text = g.shareCodeInput + "_"
+
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeyEnter):
+		g.submitShareCode()
+
+	case inpututil.IsKeyJustPressed(ebiten.KeyEscape):
+		g.dialogStack.CloseById("share-code")
+	}
+
+	return true
+}
+
+func (g *Game) submitShareCode() {
+	sc, err := ParseShareCode(g.shareCodeInput)
+	if err != nil {
+		if dialog := g.dialogStack.ById("share-code"); dialog != nil {
+			dialog.Texts[3].Text = "That code does not look right, mind checking it again?"
+		}
+
+		return
+	}
+
+	g.dialogStack.CloseById("share-code")
+
+	// the config and the mode are kept for all following levels
+	g.config = sc.Config()
+	g.timeAttack = sc.TimeAttack
+
+	g.resetOnUpdate = &ResetOnUpdate{
+		NextSeed:   sc.Seed,
+		WantSimple: sc.Simple,
+		Players:    sc.Players,
+		Bots:       sc.Bots,
+	}
+}
+
+// copyShareCodeButton copies the code of the current level to the clipboard
+func (g *Game) copyShareCodeButton() *Button {
+	button := NewButton("Copy level code", HudButtonColors).WithAutoSize()
+
+	button.OnClick = func() {
+		sc, ok := g.shareCode()
+		if !ok {
+			button.Text = "Custom level, no code"
+			return
+		}
+
+		button.Text = "Copying..."
+
+		CopyToClipboard(sc.String(), func(err error) {
+			if err != nil {
+				fmt.Printf("[err] copy to clipboard: %s\n", err)
+
+				// show the code instead, it can still be typed off the screen
+				button.Text = sc.String()
+				return
+			}
+
+			button.Text = "Copied!"
+		})
+	}
+
+	return button
+}
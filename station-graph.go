@@ -68,6 +68,61 @@ func (sg *StationGraph) HasConnections(station *Station) bool {
 	return false
 }
 
+// ConnectedCount returns the number of stations with at least one connection
+func (sg *StationGraph) ConnectedCount() int {
+	var count int
+
+	for _, station := range sg.Stations {
+		if sg.HasConnections(station) {
+			count += 1
+		}
+	}
+
+	return count
+}
+
+func (sg *StationGraph) VillageIsConnected(village *Village) bool {
+	for _, station := range sg.Stations {
+		if station.Village == village && sg.HasConnections(station) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsConnected checks if every station can be reached from every other station
+func (sg *StationGraph) IsConnected() bool {
+	if len(sg.Stations) == 0 {
+		return false
+	}
+
+	var seen Set[*Station]
+
+	queue := make([]*Station, 0, len(sg.Stations))
+
+	initial := sg.Stations[0]
+	queue = append(queue, initial)
+	seen.Insert(initial)
+
+	for idx := 0; idx < len(queue); idx++ {
+		current := queue[idx]
+
+		for _, edge := range sg.EdgesOf(current) {
+			other := edge.OtherStation(current)
+
+			if seen.Has(other) {
+				continue
+			}
+
+			queue = append(queue, other)
+			seen.Insert(other)
+		}
+	}
+
+	return seen.Len() == len(sg.Stations)
+}
+
 func (sg *StationGraph) TotalPrice() Coins {
 	var coinsTotal Coins
 	for _, edge := range sg.Edges() {
@@ -94,6 +149,11 @@ func (edge StationEdge) Price() Coins {
 	return priceOf(edge.One, edge.Two)
 }
 
+// Length returns the length of the track in meters
+func (edge StationEdge) Length() float64 {
+	return edge.One.Position.DistanceTo(edge.Two.Position)
+}
+
 func (edge StationEdge) Contains(other *Station) bool {
 	return edge.One == other || edge.Two == other
 }
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"cmp"
 	. "github.com/quasilyte/gmath"
+	"math"
 	"math/rand/v2"
+	"slices"
 )
 
 type Station struct {
@@ -10,38 +13,57 @@ type Station struct {
 
 	// the village that belongs to this station
 	Village *Village
+
+	// terrain the tracks are built on, changes the price of a track
+	terrain *Terrain
 }
 
-func GenerateStations(rng *rand.Rand, clip Rect, villages []*Village) []*Station {
-	var stations []*Station
+// minimum distance between any two stations
+const stationMinDistance = 750
 
-	for _, village := range villages {
-		newStations, _, _ := MaxOf(
-			Repeat(5, func() []*Station { return generateStations(rng, clip, village) }),
-			stationScore,
-		)
+// GenerateStations places the stations of each village at the centers of its street density.
+// Streets are clustered using a weighted k-means, weighted by the length of the streets.
+// Villages are processed in parallel, each with its own rng derived from the given one.
+func GenerateStations(rng *rand.Rand, clip Rect, terrain *Terrain, villages []*Village) []*Station {
+	type job struct {
+		Village *Village
+		Seed    uint64
+	}
 
-		stations = append(stations, newStations...)
+	// derive the seeds up front, the result must not depend on the scheduling
+	jobs := make([]job, len(villages))
+	for idx, village := range villages {
+		jobs[idx] = job{Village: village, Seed: rng.Uint64()}
 	}
 
-	return stations
+	results := parallelMap(jobs, func(idle *IdleSuspend, job job) []*Station {
+		idle.MaybeSuspend()
+		return generateVillageStations(RandWithSeed(job.Seed), clip, terrain, job.Village)
+	})
+
+	// villages do not know about each other, space the stations of neighbouring villages afterwards
+	return spaceStations(slices.Concat(results...))
 }
 
-func stationScore(stations []*Station) float64 {
-	var distanceSum float64
+// spaceStations drops every station that is too close to one kept before it.
+// The stations are visited in order, the result is the same for every run.
+func spaceStations(stations []*Station) []*Station {
+	var kept []*Station
 
-	for _, a := range stations {
-		for _, b := range stations {
-			distanceSum += a.Position.DistanceTo(b.Position)
+	for _, station := range stations {
+		tooClose := slices.ContainsFunc(kept, func(other *Station) bool {
+			return other.Position.DistanceTo(station.Position) < stationMinDistance
+		})
+
+		if !tooClose {
+			kept = append(kept, station)
 		}
 	}
 
-	return distanceSum
+	return kept
 }
 
-func generateStations(rng *rand.Rand, clip Rect, village *Village) []*Station {
-	var loc Vec
-
+func generateVillageStations(rng *rand.Rand, clip Rect, terrain *Terrain, village *Village) []*Station {
 	// get the segments that lay within the clip bounds
 	segments := segmentsWithinClip(village, clip)
 	if len(segments) < 10 {
@@ -55,25 +77,178 @@ func generateStations(rng *rand.Rand, clip Rect, village *Village) []*Station {
 
 	stationCount := populationCount/1000 + 1
 
-	var stations []*Station
+	// k-means only finds a local optimum, keep the best of a few tries
+	clusters, _, _ := MaxOf(
+		Repeat(5, func() []densityCluster { return clusterDensity(rng, segments, stationCount) }),
+		func(clusters []densityCluster) float64 { return -inertiaOf(clusters) },
+	)
+
+	return placeStations(clip, terrain, village, clusters)
+}
+
+// densityCluster is a cluster of streets, the center is the weighted mean of the streets
+type densityCluster struct {
+	Center   Vec
+	Segments []*Segment
+
+	// total length of the streets in the cluster
+	Weight float64
+}
+
+func clusterDensity(rng *rand.Rand, segments []*Segment, count int) []densityCluster {
+	centers := seedCenters(rng, segments, count)
+
+	var clusters []densityCluster
+
+	// lloyd relaxation
+	for range 20 {
+		clusters = make([]densityCluster, len(centers))
+
+		for _, segment := range segments {
+			idx := nearestCenter(centers, segment.Center())
+			clusters[idx].Segments = append(clusters[idx].Segments, segment)
+		}
+
+		var moved float64
+
+		for idx := range clusters {
+			cluster := &clusters[idx]
+
+			var sum Vec
+			for _, segment := range cluster.Segments {
+				weight := segment.Length()
+				sum = sum.Add(segment.Center().Mulf(weight))
+				cluster.Weight += weight
+			}
+
+			cluster.Center = centers[idx]
+			if cluster.Weight > 0 {
+				cluster.Center = sum.Mulf(1 / cluster.Weight)
+			}
+
+			moved = max(moved, cluster.Center.DistanceTo(centers[idx]))
+			centers[idx] = cluster.Center
+		}
 
-	for range stationCount {
-		for {
-			loc = Choose(rng, segments...).BBox().Center()
+		if moved < 1 {
+			// converged
+			break
+		}
+	}
+
+	return clusters
+}
+
+// seedCenters picks the initial centers using k-means++, weighted by street length
+func seedCenters(rng *rand.Rand, segments []*Segment, count int) []Vec {
+	var centers []Vec
 
-			if !clip.Contains(loc) {
-				// discard this, it is outside of the region
-				continue
+	weights := make([]float64, len(segments))
+
+	for range count {
+		for idx, segment := range segments {
+			weight := segment.Length()
+
+			if len(centers) > 0 {
+				// prefer streets far away from the existing centers
+				distance := segment.Center().DistanceTo(centers[nearestCenter(centers, segment.Center())])
+				weight *= distance * distance
 			}
 
-			if PointInConvexHull(village.Hull, loc) {
-				break
+			weights[idx] = weight
+		}
+
+		centers = append(centers, segments[chooseWeighted(rng, weights)].Center())
+	}
+
+	return centers
+}
+
+func chooseWeighted(rng *rand.Rand, weights []float64) int {
+	var total float64
+	for _, weight := range weights {
+		total += weight
+	}
+
+	value := rng.Float64() * total
+
+	for idx, weight := range weights {
+		value -= weight
+		if value < 0 {
+			return idx
+		}
+	}
+
+	return len(weights) - 1
+}
+
+func nearestCenter(centers []Vec, pos Vec) int {
+	nearest, nearestDistance := 0, math.Inf(1)
+
+	for idx, center := range centers {
+		if distance := center.DistanceSquaredTo(pos); distance < nearestDistance {
+			nearest, nearestDistance = idx, distance
+		}
+	}
+
+	return nearest
+}
+
+// inertiaOf sums the weighted squared distances of the streets to their cluster centers
+func inertiaOf(clusters []densityCluster) float64 {
+	var inertia float64
+
+	for _, cluster := range clusters {
+		for _, segment := range cluster.Segments {
+			inertia += segment.Length() * segment.Center().DistanceSquaredTo(cluster.Center)
+		}
+	}
+
+	return inertia
+}
+
+// placeStations puts a station on the street nearest to each cluster center that satisfies all constraints.
+// The center itself might be in a park, a river or between two arms of the village.
+func placeStations(clip Rect, terrain *Terrain, village *Village, clusters []densityCluster) []*Station {
+	// place the stations of the busiest clusters first
+	clusters = slices.Clone(clusters)
+	slices.SortStableFunc(clusters, func(a, b densityCluster) int {
+		return cmp.Compare(b.Weight, a.Weight)
+	})
+
+	var stations []*Station
+
+	isValid := func(pos Vec) bool {
+		if !clip.Contains(pos) || !village.Contains(pos) || terrain.IsWater(pos) {
+			return false
+		}
+
+		for _, station := range stations {
+			if station.Position.DistanceTo(pos) < stationMinDistance {
+				return false
 			}
 		}
 
+		return true
+	}
+
+	for _, cluster := range clusters {
+		candidates := pointsOf(cluster.Segments)
+
+		slices.SortStableFunc(candidates, func(a, b Vec) int {
+			return cmp.Compare(a.DistanceSquaredTo(cluster.Center), b.DistanceSquaredTo(cluster.Center))
+		})
+
+		idx := slices.IndexFunc(candidates, isValid)
+		if idx < 0 {
+			// no place for a station in this cluster, too close to the others
+			continue
+		}
+
 		stations = append(stations, &Station{
-			Position: loc,
+			Position: candidates[idx],
 			Village:  village,
+			terrain:  terrain,
 		})
 	}
 
@@ -28,5 +28,11 @@ func (s *Stats) CoinsAvailable() Coins {
 
 func priceOf(one, two *Station) Coins {
 	price := one.Position.DistanceTo(two.Position)
+
+	if one.terrain != nil {
+		// bridges, forests, slopes and tunnels make a track more expensive
+		price *= one.terrain.TrackCostFactor(one.Position, two.Position)
+	}
+
 	return Coins(math.Ceil(price/100) * 10)
 }
@@ -0,0 +1,189 @@
+//go:build !headless
+
+package main
+
+import (
+	"github.com/furui/fastnoiselite-go"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	. "github.com/quasilyte/gmath"
+	"math"
+)
+
+func (s *Segment) Draw(target *ebiten.Image, g ebiten.GeoM) {
+	dir := s.End.Sub(s.Start).Normalized()
+
+	start := s.Start.Sub(dir.Mulf(4.0))
+	end := s.End.Add(dir.Mulf(4.0))
+
+	x0, y0 := g.Apply(start.X, start.Y)
+	x1, y1 := g.Apply(end.X, end.Y)
+
+	class := s.Type.Class()
+
+	vector.StrokeLine(target, float32(x0), float32(y0), float32(x1), float32(y1), float32(class.Width), class.Color, true)
+}
+
+func populationToImage(noise *fastnoiselite.FastNoiseLite, width, height int, toWorld ebiten.GeoM) *ebiten.Image {
+	pixels := make([]uint8, width*height*4)
+
+	var pos int
+	for y := range height {
+		for x := range width {
+			trX, trY := toWorld.Apply(float64(x), float64(y))
+
+			noiseValue := populationValueAt(noise, Vec{X: trX, Y: trY})
+
+			pxValue := uint8(noiseValue * 0xff)
+
+			if noiseValue > 0.25 {
+				pixels[pos+1] = pxValue
+			}
+
+			pixels[pos+3] = pxValue
+
+			pos += 4
+		}
+	}
+
+	img := ebiten.NewImage(width, height)
+	img.WritePixels(pixels)
+	return img
+}
+
+func noiseToImage(noise *fastnoiselite.FastNoiseLite, width, height int, toWorld ebiten.GeoM) *ebiten.Image {
+	pixels := make([]uint8, width*height*4)
+
+	var pos int
+	for y := range height {
+		for x := range width {
+			trX, trY := toWorld.Apply(float64(x), float64(y))
+
+			noiseValue := noise.GetNoise2D(fastnoiselite.FNLfloat(trX), fastnoiselite.FNLfloat(trY))
+
+			pxValue := uint8((noiseValue + 1) / 2 * 0xff)
+			pixels[pos+0] = pxValue
+			pixels[pos+1] = pxValue
+			pixels[pos+2] = pxValue
+			pixels[pos+3] = 0xff
+
+			pos += 4
+		}
+	}
+
+	img := ebiten.NewImage(width, height)
+	img.WritePixels(pixels)
+	return img
+}
+
+type RenderSegments struct {
+	VerticesChunks [][]ebiten.Vertex
+	IndicesChunks  [][]uint16
+
+	Dirty bool
+
+	// chunk and index of the first triangle not yet drawn
+	pendingChunk int
+	pendingIndex int
+
+	tempVertices []ebiten.Vertex
+}
+
+func (r *RenderSegments) Add(s *Segment, toWorld ebiten.GeoM) {
+	r.Dirty = true
+
+	dir := s.End.Sub(s.Start).Normalized()
+
+	start := s.Start.Sub(dir.Mulf(4.0)).AsVec32()
+	end := s.End.Add(dir.Mulf(4.0)).AsVec32()
+
+	class := s.Type.Class()
+	strokeWidth, strokeColor := class.Width, class.Color
+
+	chunksCount := len(r.VerticesChunks)
+	if chunksCount == 0 || len(r.VerticesChunks[chunksCount-1]) > math.MaxUint16-128 {
+		r.VerticesChunks = append(r.VerticesChunks, nil)
+		r.IndicesChunks = append(r.IndicesChunks, nil)
+	}
+
+	chunkIdx := len(r.VerticesChunks) - 1
+
+	// find a chunk that we'll write the segments to
+	vertices := &r.VerticesChunks[chunkIdx]
+	indices := &r.IndicesChunks[chunkIdx]
+
+	// get the index where we place the new vertices
+	vertexStart := len(*vertices)
+
+	// create a path
+	var path vector.Path
+	path.MoveTo(start.X, start.Y)
+	path.LineTo(end.X, end.Y)
+
+	// append vertices from path to chunks
+	strokeOp := &vector.StrokeOptions{}
+	strokeOp.Width = float32(TransformScalar(toWorld, strokeWidth))
+	*vertices, *indices = path.AppendVerticesAndIndicesForStroke(*vertices, *indices, strokeOp)
+
+	for v := vertexStart; v < len(*vertices); v++ {
+		(*vertices)[v].ColorR = float32(strokeColor.R) / 255
+		(*vertices)[v].ColorG = float32(strokeColor.G) / 255
+		(*vertices)[v].ColorB = float32(strokeColor.B) / 255
+		(*vertices)[v].ColorA = float32(strokeColor.A) / 255
+	}
+}
+
+// Draw draws all segments to the screen
+func (r *RenderSegments) Draw(screen *ebiten.Image, toScreen ebiten.GeoM) {
+	r.drawFrom(screen, toScreen, 0, 0)
+}
+
+// DrawPending draws only the segments that were added since the last call to Draw or DrawPending
+func (r *RenderSegments) DrawPending(screen *ebiten.Image, toScreen ebiten.GeoM) {
+	r.drawFrom(screen, toScreen, r.pendingChunk, r.pendingIndex)
+}
+
+func (r *RenderSegments) drawFrom(screen *ebiten.Image, toScreen ebiten.GeoM, firstChunk, firstIndex int) {
+	r.Dirty = false
+
+	for chunk := firstChunk; chunk < len(r.VerticesChunks); chunk++ {
+		vertices := r.VerticesChunks[chunk]
+		indices := r.IndicesChunks[chunk]
+
+		if chunk == firstChunk {
+			indices = indices[firstIndex:]
+		}
+
+		if len(indices) == 0 {
+			continue
+		}
+
+		r.tempVertices = TransformVertices(toScreen, vertices, r.tempVertices[:0])
+
+		// render vertices
+		op := &ebiten.DrawTrianglesOptions{}
+		op.AntiAlias = true
+		screen.DrawTriangles(r.tempVertices, indices, whiteImage, op)
+	}
+
+	// remember what we have drawn so far
+	if count := len(r.IndicesChunks); count > 0 {
+		r.pendingChunk = count - 1
+		r.pendingIndex = len(r.IndicesChunks[count-1])
+	}
+}
+
+func (r *RenderSegments) Clear() {
+	r.Dirty = false
+
+	r.pendingChunk = 0
+	r.pendingIndex = 0
+
+	if len(r.VerticesChunks) > 0 {
+		r.VerticesChunks = [][]ebiten.Vertex{r.VerticesChunks[0][:0]}
+	}
+
+	if len(r.IndicesChunks) > 0 {
+		r.IndicesChunks = [][]uint16{r.IndicesChunks[0][:0]}
+	}
+}
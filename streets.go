@@ -2,9 +2,8 @@ package main
 
 import (
 	"github.com/furui/fastnoiselite-go"
-	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/vector"
 	. "github.com/quasilyte/gmath"
+	"image/color"
 	"iter"
 	"math"
 	"math/rand/v2"
@@ -12,8 +11,80 @@ import (
 
 type StreetType uint8
 
-const StreetTypeHighway = 0
-const StreetTypeLocal = 1
+const (
+	// long distance roads between towns
+	StreetTypeHighway StreetType = iota
+
+	// main street of a town, branching off the highway
+	StreetTypeArterial
+
+	// connects the neighbourhoods to the main street
+	StreetTypeCollector
+
+	StreetTypeLocal
+
+	// short dead-end streets
+	StreetTypeLane
+)
+
+// StreetClass describes how streets of a type are generated and drawn
+type StreetClass struct {
+	Width float64
+	Color color.NRGBA
+
+	// chance to stop the street at each segment
+	StopChance float64
+
+	// minimum distance between two side streets
+	ForkDistance float64
+
+	// type of the side streets branching off
+	Child StreetType
+}
+
+var streetClasses = [...]StreetClass{
+	StreetTypeHighway: {
+		Width:        2.0,
+		Color:        rgbaOf(0x978c63ff),
+		ForkDistance: 100,
+		Child:        StreetTypeArterial,
+	},
+
+	StreetTypeArterial: {
+		Width:        1.6,
+		Color:        rgbaOf(0xa39668ff),
+		StopChance:   0.02,
+		ForkDistance: 150,
+		Child:        StreetTypeCollector,
+	},
+
+	StreetTypeCollector: {
+		Width:        1.3,
+		Color:        rgbaOf(0xaea06eff),
+		StopChance:   0.05,
+		ForkDistance: 120,
+		Child:        StreetTypeLocal,
+	},
+
+	StreetTypeLocal: {
+		Width:        1.0,
+		Color:        rgbaOf(0xb9ab73ff),
+		StopChance:   0.1,
+		ForkDistance: 100,
+		Child:        StreetTypeLocal,
+	},
+
+	StreetTypeLane: {
+		Width:        0.7,
+		Color:        rgbaOf(0xc4b784ff),
+		StopChance:   0.35,
+		ForkDistance: math.Inf(1),
+	},
+}
+
+func (t StreetType) Class() StreetClass {
+	return streetClasses[t]
+}
 
 type PendingSegment struct {
 	PreviousSegment        *Segment
@@ -22,6 +93,13 @@ type PendingSegment struct {
 	DistanceToPreviousFork float64
 	Type                   StreetType
 	AtStep                 int
+
+	// layout of the town a local street belongs to, and its center
+	Style  TownStyle
+	Origin Vec
+
+	// the town center a highway is heading to, nil for free roaming streets
+	Target *Vec
 }
 
 type Line struct {
@@ -103,25 +181,6 @@ func (s *Segment) Intersection(other *Segment) (Vec, bool) {
 	return s.Line.Intersection(other.Line)
 }
 
-func (s *Segment) Draw(target *ebiten.Image, g ebiten.GeoM) {
-	dir := s.End.Sub(s.Start).Normalized()
-
-	start := s.Start.Sub(dir.Mulf(4.0))
-	end := s.End.Add(dir.Mulf(4.0))
-
-	x0, y0 := g.Apply(start.X, start.Y)
-	x1, y1 := g.Apply(end.X, end.Y)
-
-	strokeWidth := 2.0
-	strokeColor := rgbaOf(0x978c63ff)
-	if s.Type == StreetTypeLocal {
-		strokeWidth = 1.0
-		strokeColor = rgbaOf(0xb9ab73ff)
-	}
-
-	vector.StrokeLine(target, float32(x0), float32(y0), float32(x1), float32(y1), float32(strokeWidth), strokeColor, true)
-}
-
 func (s *Segment) IsConnected(other *Segment) bool {
 	for _, connected := range s.Connections {
 		if connected == other {
@@ -152,25 +211,32 @@ type StreetGenerator struct {
 	Clip          Rect
 	rng           *rand.Rand
 	noise         *fastnoiselite.FastNoiseLite
+	styleNoise    *fastnoiselite.FastNoiseLite
 	segmentsQueue Heap[PendingSegment]
 	segments      []*Segment
 	grid          Grid[*Segment]
 	terrain       Terrain
+
+	// max distance when to connect to existing segments
+	connectThreshold float64
 }
 
-func NewStreetGenerator(rng *rand.Rand, clip Rect, terrain Terrain) StreetGenerator {
+func NewStreetGenerator(rng *rand.Rand, clip Rect, terrain Terrain, config GenerationConfig) StreetGenerator {
 	noise := fastnoiselite.NewNoise()
 	noise.SetNoiseType(fastnoiselite.NoiseTypeValueCubic)
 	noise.Seed = rng.Int32()
-	noise.Frequency = 0.0008
+	noise.Frequency = config.PopulationFrequency
 
 	return StreetGenerator{
 		Clip:          clip,
 		rng:           rng,
 		noise:         noise,
+		styleNoise:    newStyleNoise(noise.Seed + 1),
 		terrain:       terrain,
 		segmentsQueue: NewPendingSegmentQueue(),
 		grid:          NewGrid[*Segment](vecSplat(50), nil),
+
+		connectThreshold: config.ConnectThreshold,
 	}
 }
 
@@ -206,7 +272,7 @@ func (gen *StreetGenerator) Next() *Segment {
 
 	// kill the segment if it reaches the river
 	if line, _, ok := gen.intersectsWater(segment); ok {
-		if segment.Type == StreetTypeLocal {
+		if segment.Type != StreetTypeHighway {
 			// discard, small streets never cross water
 			return nil
 		}
@@ -224,14 +290,18 @@ func (gen *StreetGenerator) Next() *Segment {
 		segment.End = segment.End.Add(dirSegment.Mulf(1500))
 	}
 
+	if gen.terrain.IsStillWater(segment.End) || gen.terrain.IsStillWater(segment.Center()) {
+		// no street is built into a lake or the sea
+		return nil
+	}
+
 	gen.segments = append(gen.segments, segment)
 
 	// only add to index at the end, we might still change
 	// the points
 	defer gen.grid.Insert(segment)
 
-	// max distance when to connect to existing segments
-	const connectThreshold = 30
+	connectThreshold := gen.connectThreshold
 
 	// check if we can find a point very near to our segments end
 	bbox5 := segment.BBox()
@@ -245,12 +315,14 @@ func (gen *StreetGenerator) Next() *Segment {
 		if existing.End.DistanceSquaredTo(segment.End) < connectThreshold*connectThreshold {
 			segment.Connect(existing)
 			segment.End = existing.End
+			gen.continueToTarget(prev, segment)
 			return segment
 		}
 
 		if existing.Start.DistanceSquaredTo(segment.End) < connectThreshold*connectThreshold {
 			segment.Connect(existing)
 			segment.End = existing.Start
+			gen.continueToTarget(prev, segment)
 			return segment
 		}
 	}
@@ -272,18 +344,29 @@ func (gen *StreetGenerator) Next() *Segment {
 				segment.End = point
 			}
 
+			// highways between towns cross other streets
+			gen.continueToTarget(prev, segment)
+
 			return segment
 		}
 	}
 
 	const localStreetDensityThreshold = 0.25
+	const forestStopChance = 0.2
 	const highwayForkThreshold = 0.1
 
-	if prev.Type == StreetTypeLocal {
-		if gen.PopulationAt(prev.Point) < localStreetDensityThreshold || prob(gen.rng, 0.1) {
+	class := prev.Type.Class()
+
+	if prev.Type != StreetTypeHighway {
+		if gen.PopulationAt(prev.Point) < localStreetDensityThreshold || prob(gen.rng, class.StopChance) {
 			// population not dense enough, stop here
 			return nil
 		}
+
+		if gen.terrain.IsForest(segment.End) && prob(gen.rng, forestStopChance) {
+			// few people live in the woods
+			return nil
+		}
 	}
 
 	if segment.Type == StreetTypeHighway {
@@ -307,17 +390,23 @@ func (gen *StreetGenerator) Next() *Segment {
 		}
 	}
 
-	gen.segmentsQueue.Push(PendingSegment{
-		PreviousSegment:        segment,
-		Point:                  segment.End,
-		Angle:                  segment.Angle(),
-		DistanceToPreviousFork: distanceToPreviousFork + segment.Length(),
-		Type:                   prev.Type,
-		AtStep:                 prev.AtStep + 10,
-	})
+	if prev.Target != nil {
+		gen.continueToTarget(prev, segment)
+	} else {
+		gen.segmentsQueue.Push(PendingSegment{
+			PreviousSegment:        segment,
+			Point:                  segment.End,
+			Angle:                  segment.Angle(),
+			DistanceToPreviousFork: distanceToPreviousFork + segment.Length(),
+			Type:                   prev.Type,
+			AtStep:                 prev.AtStep + 10,
+			Style:                  prev.Style,
+			Origin:                 prev.Origin,
+		})
+	}
 
-	// if this is a high population neighbourhood, we create a small street
-	if gen.PopulationAt(segment.End) > localStreetDensityThreshold && distanceToPreviousFork > 100.0 {
+	// if this is a high population neighbourhood, we create a smaller side street
+	if gen.PopulationAt(segment.End) > localStreetDensityThreshold && distanceToPreviousFork > class.ForkDistance {
 		var nextAtStep int
 
 		if prev.Type == StreetTypeHighway {
@@ -326,14 +415,38 @@ func (gen *StreetGenerator) Next() *Segment {
 			nextAtStep = prev.AtStep + 200
 		}
 
-		gen.segmentsQueue.Push(PendingSegment{
-			PreviousSegment:        segment,
-			Point:                  segment.End,
-			Angle:                  segment.Angle() + DegToRad(90)*Rad(Choose(gen.rng, -1, +1)),
-			DistanceToPreviousFork: 0,
-			Type:                   StreetTypeLocal,
-			AtStep:                 nextAtStep,
-		})
+		style, origin := prev.Style, prev.Origin
+		if prev.Type == StreetTypeHighway {
+			// a street branching off the highway belongs to a new town
+			style = gen.StyleAt(segment.End)
+			origin = gen.townCenterNear(segment.End)
+		}
+
+		child := class.Child
+		if child == StreetTypeLocal && prob(gen.rng, 0.3) {
+			child = StreetTypeLane
+		}
+
+		sides := []Rad{Rad(Choose(gen.rng, -1, +1))}
+
+		if style == TownStyleRibbon && prev.Type == StreetTypeHighway {
+			// lanes line up on both sides of the highway
+			sides = []Rad{-1, +1}
+			child = StreetTypeLane
+		}
+
+		for _, side := range sides {
+			gen.segmentsQueue.Push(PendingSegment{
+				PreviousSegment:        segment,
+				Point:                  segment.End,
+				Angle:                  segment.Angle() + DegToRad(90)*side,
+				DistanceToPreviousFork: 0,
+				Type:                   child,
+				AtStep:                 nextAtStep,
+				Style:                  style,
+				Origin:                 origin,
+			})
+		}
 	}
 
 	// tell the caller if we need to be called again
@@ -344,6 +457,14 @@ func (gen *StreetGenerator) Push(p PendingSegment) {
 	gen.segmentsQueue.Push(p)
 }
 
+// Restore replaces all streets with the given ones, e.g. the streets of a cached level.
+// Nothing is left to generate afterwards.
+func (gen *StreetGenerator) Restore(segments []*Segment) {
+	gen.segmentsQueue = NewPendingSegmentQueue()
+	gen.segments = segments
+	gen.grid = NewGrid[*Segment](vecSplat(50), segments)
+}
+
 func (gen *StreetGenerator) Segments() []*Segment {
 	return gen.segments
 }
@@ -352,8 +473,21 @@ func (gen *StreetGenerator) nextSegment(previousSegment PendingSegment, maxAngle
 	start := previousSegment.Point
 	previousAngle := previousSegment.Angle
 
-	// get the next vector for the new segment
-	end := gen.nextVec(start, previousAngle, maxAngle)
+	var end Vec
+
+	switch {
+	case previousSegment.Target != nil:
+		end = gen.nextVecToward(start, previousAngle, *previousSegment.Target)
+
+	case previousSegment.Style == TownStyleGrid, previousSegment.Style == TownStyleRadial:
+		// planned towns follow their layout, not the population
+		length := Randf(gen.rng, 60.0, 70.0)
+		end = start.Add(Vec{X: length}.Rotated(gen.styledAngle(previousSegment)))
+
+	default:
+		// get the next vector for the new segment
+		end = gen.nextVec(start, previousAngle, maxAngle)
+	}
 
 	newSegment := Segment{
 		Line: Line{
@@ -369,6 +503,9 @@ func (gen *StreetGenerator) nextSegment(previousSegment PendingSegment, maxAngle
 	return &newSegment
 }
 
+// weight of the height difference when choosing the direction of a street
+const streetClimbPenalty = 2.0
+
 func (gen *StreetGenerator) nextVec(pos Vec, prevAngle Rad, maxAngle Rad) Vec {
 	var best Vec
 	var bestValue float64 = -1
@@ -381,9 +518,12 @@ func (gen *StreetGenerator) nextVec(pos Vec, prevAngle Rad, maxAngle Rad) Vec {
 		// the segment offset from the start pos
 		offset := Vec{X: length}.Rotated(angle)
 
+		// streets rather follow the contour lines than climb up a hill
+		climb := math.Abs(gen.terrain.HeightAt(pos.Add(offset)) - gen.terrain.HeightAt(pos))
+
 		for scale := range 10 {
 			// look a little ahead and sample the population values
-			noiseValue := gen.PopulationAt(pos.Add(offset.Mulf(5.0 + 2.0*float64(scale))))
+			noiseValue := gen.PopulationAt(pos.Add(offset.Mulf(5.0+2.0*float64(scale)))) - climb*streetClimbPenalty
 			if noiseValue > bestValue {
 				bestValue = noiseValue
 				best = pos.Add(offset)
@@ -415,6 +555,10 @@ outer:
 	for {
 		loc := RandVecIn(gen.rng, gen.Clip)
 
+		if gen.terrain.IsWater(loc) {
+			continue
+		}
+
 		for pending := range gen.segmentsQueue.Values() {
 			if pending.Point.DistanceTo(loc) < distanceThreshold {
 				continue outer
@@ -497,58 +641,6 @@ func lineIntersection(p1, p2, q1, q2 Vec) (Vec, bool) {
 	return p1.Add(p2.Sub(p1).Mulf(t)), ok
 }
 
-func populationToImage(noise *fastnoiselite.FastNoiseLite, width, height int, toWorld ebiten.GeoM) *ebiten.Image {
-	pixels := make([]uint8, width*height*4)
-
-	var pos int
-	for y := range height {
-		for x := range width {
-			trX, trY := toWorld.Apply(float64(x), float64(y))
-
-			noiseValue := populationValueAt(noise, Vec{X: trX, Y: trY})
-
-			pxValue := uint8(noiseValue * 0xff)
-
-			if noiseValue > 0.25 {
-				pixels[pos+1] = pxValue
-			}
-
-			pixels[pos+3] = pxValue
-
-			pos += 4
-		}
-	}
-
-	img := ebiten.NewImage(width, height)
-	img.WritePixels(pixels)
-	return img
-}
-
-func noiseToImage(noise *fastnoiselite.FastNoiseLite, width, height int, toWorld ebiten.GeoM) *ebiten.Image {
-	pixels := make([]uint8, width*height*4)
-
-	var pos int
-	for y := range height {
-		for x := range width {
-			trX, trY := toWorld.Apply(float64(x), float64(y))
-
-			noiseValue := noise.GetNoise2D(fastnoiselite.FNLfloat(trX), fastnoiselite.FNLfloat(trY))
-
-			pxValue := uint8((noiseValue + 1) / 2 * 0xff)
-			pixels[pos+0] = pxValue
-			pixels[pos+1] = pxValue
-			pixels[pos+2] = pxValue
-			pixels[pos+3] = 0xff
-
-			pos += 4
-		}
-	}
-
-	img := ebiten.NewImage(width, height)
-	img.WritePixels(pixels)
-	return img
-}
-
 type HasBBox interface {
 	comparable
 	BBox() Rect
@@ -649,87 +741,3 @@ func (g *Grid[T]) innerCellOf(id cellId, create bool) *GridCell[T] {
 
 	return cell
 }
-
-type RenderSegments struct {
-	VerticesChunks [][]ebiten.Vertex
-	IndicesChunks  [][]uint16
-
-	Dirty        bool
-	tempVertices []ebiten.Vertex
-}
-
-func (r *RenderSegments) Add(s *Segment, toWorld ebiten.GeoM) {
-	r.Dirty = true
-
-	dir := s.End.Sub(s.Start).Normalized()
-
-	start := s.Start.Sub(dir.Mulf(4.0)).AsVec32()
-	end := s.End.Add(dir.Mulf(4.0)).AsVec32()
-
-	strokeWidth := 2.0
-	strokeColor := rgbaOf(0x978c63ff)
-	if s.Type == StreetTypeLocal {
-		strokeWidth = 1.0
-		strokeColor = rgbaOf(0xb9ab73ff)
-	}
-
-	chunksCount := len(r.VerticesChunks)
-	if chunksCount == 0 || len(r.VerticesChunks[chunksCount-1]) > math.MaxUint16-128 {
-		r.VerticesChunks = append(r.VerticesChunks, nil)
-		r.IndicesChunks = append(r.IndicesChunks, nil)
-	}
-
-	chunkIdx := len(r.VerticesChunks) - 1
-
-	// find a chunk that we'll write the segments to
-	vertices := &r.VerticesChunks[chunkIdx]
-	indices := &r.IndicesChunks[chunkIdx]
-
-	// get the index where we place the new vertices
-	vertexStart := len(*vertices)
-
-	// create a path
-	var path vector.Path
-	path.MoveTo(start.X, start.Y)
-	path.LineTo(end.X, end.Y)
-
-	// append vertices from path to chunks
-	strokeOp := &vector.StrokeOptions{}
-	strokeOp.Width = float32(TransformScalar(toWorld, strokeWidth))
-	*vertices, *indices = path.AppendVerticesAndIndicesForStroke(*vertices, *indices, strokeOp)
-
-	for v := vertexStart; v < len(*vertices); v++ {
-		(*vertices)[v].ColorR = float32(strokeColor.R) / 255
-		(*vertices)[v].ColorG = float32(strokeColor.G) / 255
-		(*vertices)[v].ColorB = float32(strokeColor.B) / 255
-		(*vertices)[v].ColorA = float32(strokeColor.A) / 255
-	}
-}
-
-func (r *RenderSegments) Draw(screen *ebiten.Image, toScreen ebiten.GeoM) {
-	r.Dirty = false
-
-	for chunk := range r.VerticesChunks {
-		vertices := r.VerticesChunks[chunk]
-		indices := r.IndicesChunks[chunk]
-
-		r.tempVertices = TransformVertices(toScreen, vertices, r.tempVertices[:0])
-
-		// render vertices
-		op := &ebiten.DrawTrianglesOptions{}
-		op.AntiAlias = true
-		screen.DrawTriangles(r.tempVertices, indices, whiteImage, op)
-	}
-}
-
-func (r *RenderSegments) Clear() {
-	r.Dirty = false
-
-	if len(r.VerticesChunks) > 0 {
-		r.VerticesChunks = [][]ebiten.Vertex{r.VerticesChunks[0][:0]}
-	}
-
-	if len(r.IndicesChunks) > 0 {
-		r.IndicesChunks = [][]uint16{r.IndicesChunks[0][:0]}
-	}
-}
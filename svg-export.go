@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	. "github.com/quasilyte/gmath"
+	"html"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// svgCanvas writes the map as svg elements in world coordinates
+type svgCanvas struct {
+	buf bytes.Buffer
+}
+
+// RenderSvg draws the map as an svg image.
+// It is used for printing posters and sharing finished networks.
+func RenderSvg(export MapExport) []byte {
+	var canvas svgCanvas
+
+	_, _ = fmt.Fprintf(&canvas.buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %g %g">`+"\n",
+		int(worldWidth/mapPixel), int(worldHeight/mapPixel), worldWidth, worldHeight,
+	)
+
+	drawMap(&canvas, export)
+
+	canvas.buf.WriteString("</svg>\n")
+
+	return canvas.buf.Bytes()
+}
+
+// Mesh writes all triangles as a single path. The path is drawn opaque and
+// faded as a whole, overlapping triangles would get darker otherwise.
+// Image embeds the image as a png
+func (c *svgCanvas) Image(img image.Image, rect Rect) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return
+	}
+
+	_, _ = fmt.Fprintf(&c.buf, `<image x="%.1f" y="%.1f" width="%.1f" height="%.1f" preserveAspectRatio="none" href="data:image/png;base64,%s"/>`+"\n",
+		rect.Min.X, rect.Min.Y, rect.Width(), rect.Height(),
+		base64.StdEncoding.EncodeToString(buf.Bytes()),
+	)
+}
+
+func (c *svgCanvas) Mesh(vertices []Vertex, indices []uint16, fill color.Color) {
+	if len(indices) == 0 {
+		return
+	}
+
+	opaque, alpha := svgColorOf(fill)
+
+	// the stroke hides the seams between the triangles
+	_, _ = fmt.Fprintf(&c.buf, `<path fill="%s" stroke="%s" stroke-width="%.1f" stroke-linejoin="round"`, opaque, opaque, 0.5*mapPixel)
+	if alpha < 1 {
+		_, _ = fmt.Fprintf(&c.buf, ` opacity="%.2f"`, alpha)
+	}
+
+	c.buf.WriteString(` d="`)
+
+	for idx := 0; idx+2 < len(indices); idx += 3 {
+		a, b, v := vertices[indices[idx]], vertices[indices[idx+1]], vertices[indices[idx+2]]
+		_, _ = fmt.Fprintf(&c.buf, "M%.1f %.1fL%.1f %.1fL%.1f %.1fZ", a.DstX, a.DstY, b.DstX, b.DstY, v.DstX, v.DstY)
+	}
+
+	c.buf.WriteString(`"/>` + "\n")
+}
+
+func (c *svgCanvas) Polygon(points []Vec, fill color.Color) {
+	if len(points) < 3 {
+		return
+	}
+
+	c.buf.WriteString(`<path d="`)
+
+	for idx, point := range points {
+		_, _ = fmt.Fprintf(&c.buf, "%s%.1f %.1f", iff(idx == 0, "M", "L"), point.X, point.Y)
+	}
+
+	_, _ = fmt.Fprintf(&c.buf, `Z" %s/>`+"\n", svgPaint("fill", fill))
+}
+
+func (c *svgCanvas) Lines(lines []Line, width, dash float64, stroke color.Color) {
+	if len(lines) == 0 {
+		return
+	}
+
+	_, _ = fmt.Fprintf(&c.buf, `<path fill="none" %s stroke-width="%.1f"`, svgPaint("stroke", stroke), width)
+	if dash > 0 {
+		_, _ = fmt.Fprintf(&c.buf, ` stroke-dasharray="%.1f"`, dash)
+	}
+
+	c.buf.WriteString(` d="`)
+
+	for _, line := range lines {
+		_, _ = fmt.Fprintf(&c.buf, "M%.1f %.1fL%.1f %.1f", line.Start.X, line.Start.Y, line.End.X, line.End.Y)
+	}
+
+	c.buf.WriteString(`"/>` + "\n")
+}
+
+func (c *svgCanvas) Circle(center Vec, radius float64, fill color.Color) {
+	_, _ = fmt.Fprintf(&c.buf, `<circle cx="%.1f" cy="%.1f" r="%.1f" %s/>`+"\n", center.X, center.Y, radius, svgPaint("fill", fill))
+}
+
+func (c *svgCanvas) Text(text string, pos Vec, size float64, alignRight bool, fill color.Color) {
+	_, _ = fmt.Fprintf(&c.buf, `<text x="%.1f" y="%.1f" font-family="sans-serif" font-size="%.1f" text-anchor="%s" dominant-baseline="middle" %s %s stroke-width="%.1f" paint-order="stroke">%s</text>`+"\n",
+		pos.X, pos.Y, size, iff(alignRight, "end", "middle"),
+		svgPaint("fill", fill), svgPaint("stroke", BackgroundColor), 3*mapPixel,
+		html.EscapeString(text),
+	)
+}
+
+// svgPaint returns the attributes to fill or stroke with the given color
+func svgPaint(attr string, c color.Color) string {
+	opaque, alpha := svgColorOf(c)
+	if alpha >= 1 {
+		return fmt.Sprintf(`%s="%s"`, attr, opaque)
+	}
+
+	return fmt.Sprintf(`%s="%s" %s-opacity="%.2f"`, attr, opaque, attr, alpha)
+}
+
+// svgColorOf splits a color into its opaque hex value and its alpha
+func svgColorOf(c color.Color) (string, float64) {
+	nrgba := color.NRGBAModel.Convert(c).(color.NRGBA)
+	return fmt.Sprintf("#%02x%02x%02x", nrgba.R, nrgba.G, nrgba.B), float64(nrgba.A) / 255
+}
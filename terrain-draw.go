@@ -0,0 +1,85 @@
+//go:build !headless
+
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+func (t *Terrain) Draw(target *ebiten.Image, toScreen ebiten.GeoM) {
+	var top ebiten.DrawTrianglesOptions
+	top.AntiAlias = true
+
+	if t.heights != nil {
+		t.heights.DrawShade(target, toScreen)
+	}
+
+	for _, contour := range t.Contours {
+		t.scratch = contour.Draw(target, toScreen, t.scratch)
+	}
+
+	for _, forest := range t.Forests {
+		t.scratch = forest.Draw(target, toScreen, t.scratch)
+	}
+
+	t.scratch = t.Sea.Draw(target, toScreen, t.scratch)
+
+	for _, lake := range t.Lakes {
+		t.scratch = lake.Draw(target, toScreen, t.scratch)
+	}
+
+	for _, river := range t.Rivers {
+		// bring vertices to screen
+		t.scratch = TransformVertices(toScreen, river.Vertices, t.scratch[:0])
+		target.DrawTriangles(t.scratch, river.Indices, whiteImage, &top)
+	}
+}
+
+func (t *TerrainGenerator) DebugDraw(target *ebiten.Image, toScreen ebiten.GeoM) {
+	if t.debugNoiseImage == nil {
+		toWorld := toScreen
+		toWorld.Invert()
+
+		t.debugNoiseImage = noiseToImage(t.noise, target.Bounds().Dx(), target.Bounds().Dy(), toWorld)
+	}
+
+	target.DrawImage(t.debugNoiseImage, nil)
+}
+
+// ResetDebugImage discards the cached debug image, e.g. after the screen size has changed
+func (t *TerrainGenerator) ResetDebugImage() {
+	if t.debugNoiseImage != nil {
+		t.debugNoiseImage.Deallocate()
+		t.debugNoiseImage = nil
+	}
+}
+
+func (hf *heightField) DrawShade(target *ebiten.Image, toScreen ebiten.GeoM) {
+	if hf.shade == nil {
+		hf.shade = ebiten.NewImage(hf.cols, hf.rows)
+		hf.shade.WritePixels(hf.shadePixels)
+	}
+
+	// each pixel is centered on its sample
+	var op ebiten.DrawImageOptions
+	op.GeoM.Translate(-0.5, -0.5)
+	op.GeoM.Scale(heightCellSize, heightCellSize)
+	op.GeoM.Translate(hf.world.Min.X, hf.world.Min.Y)
+	op.GeoM.Concat(toScreen)
+	op.Filter = ebiten.FilterLinear
+
+	target.DrawImage(hf.shade, &op)
+}
+
+func (m *TerrainMesh) Draw(target *ebiten.Image, toScreen ebiten.GeoM, scratch []ebiten.Vertex) []ebiten.Vertex {
+	if len(m.Indices) == 0 {
+		return scratch
+	}
+
+	var top ebiten.DrawTrianglesOptions
+	top.AntiAlias = true
+
+	scratch = TransformVertices(toScreen, m.Vertices, scratch[:0])
+	target.DrawTriangles(scratch, m.Indices, whiteImage, &top)
+	return scratch
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"github.com/furui/fastnoiselite-go"
+	. "github.com/quasilyte/gmath"
+	"image"
+	"math"
+)
+
+// distance between two samples of the height field in meters
+const heightCellSize = 100
+
+// elevation of the highest point of the world in meters
+const heightScale = 250.0
+
+// heightField samples the terrain noise on a regular grid. Heights
+// are normalized to [0, 1] over the world.
+type heightField struct {
+	world Rect
+	cols  int
+	rows  int
+
+	heights []float32
+
+	// noise the heights are sampled from, adds some smaller hills
+	noise  *fastnoiselite.FastNoiseLite
+	detail *fastnoiselite.FastNoiseLite
+
+	// raw noise values of the lowest and highest sample
+	low, high float64
+
+	// hillshading, created on first draw
+	shadePixels []byte
+	shade       *Image
+}
+
+func newHeightField(world Rect, noise, detail *fastnoiselite.FastNoiseLite) *heightField {
+	cols := int(math.Ceil(world.Width()/heightCellSize)) + 1
+	rows := int(math.Ceil(world.Height()/heightCellSize)) + 1
+
+	hf := &heightField{
+		world:   world,
+		cols:    cols,
+		rows:    rows,
+		heights: make([]float32, cols*rows),
+		noise:   noise,
+		detail:  detail,
+		low:     math.Inf(1),
+		high:    math.Inf(-1),
+	}
+
+	raw := make([]float64, cols*rows)
+	for row := range rows {
+		for col := range cols {
+			value := hf.sample(hf.posOf(col, row))
+			raw[row*cols+col] = value
+
+			hf.low = min(hf.low, value)
+			hf.high = max(hf.high, value)
+		}
+	}
+
+	for idx, value := range raw {
+		hf.heights[idx] = float32(hf.normalize(value))
+	}
+
+	hf.shadePixels = hf.hillshade()
+
+	return hf
+}
+
+func (hf *heightField) sample(pos Vec) float64 {
+	return 0.75*sampleNoise01(hf.noise, pos) + 0.25*sampleNoise01(hf.detail, pos)
+}
+
+func (hf *heightField) normalize(value float64) float64 {
+	if hf.high <= hf.low {
+		return 0
+	}
+
+	return Clamp((value-hf.low)/(hf.high-hf.low), 0, 1)
+}
+
+func (hf *heightField) posOf(col, row int) Vec {
+	return hf.world.Min.Add(Vec{X: float64(col) * heightCellSize, Y: float64(row) * heightCellSize})
+}
+
+func (hf *heightField) heightOf(col, row int) float64 {
+	return float64(hf.heights[row*hf.cols+col])
+}
+
+// At interpolates the height at the given point. Points outside
+// of the world are sampled from the noise directly.
+func (hf *heightField) At(pos Vec) float64 {
+	x := (pos.X - hf.world.Min.X) / heightCellSize
+	y := (pos.Y - hf.world.Min.Y) / heightCellSize
+
+	col, row := int(math.Floor(x)), int(math.Floor(y))
+	if col < 0 || row < 0 || col >= hf.cols-1 || row >= hf.rows-1 {
+		return hf.normalize(hf.sample(pos))
+	}
+
+	fx, fy := x-float64(col), y-float64(row)
+
+	top := hf.heightOf(col, row)*(1-fx) + hf.heightOf(col+1, row)*fx
+	bottom := hf.heightOf(col, row+1)*(1-fx) + hf.heightOf(col+1, row+1)*fx
+
+	return top*(1-fy) + bottom*fy
+}
+
+// Gradient returns the change of height per meter in both directions
+func (hf *heightField) Gradient(pos Vec) Vec {
+	const delta = heightCellSize / 2
+
+	return Vec{
+		X: (hf.At(pos.Add(Vec{X: delta})) - hf.At(pos.Sub(Vec{X: delta}))) / (2 * delta),
+		Y: (hf.At(pos.Add(Vec{Y: delta})) - hf.At(pos.Sub(Vec{Y: delta}))) / (2 * delta),
+	}
+}
+
+// hillshade calculates the pixels of the shading, one pixel per sample.
+// Slopes facing the light in the north-west are lit, the others are shaded.
+func (hf *heightField) hillshade() []byte {
+	// makes the hills look steeper than they are
+	const exaggeration = 12.0
+	const strength = 0.5
+
+	light := [3]float64{-1, -1, 1.5}
+	lightLen := math.Sqrt(light[0]*light[0] + light[1]*light[1] + light[2]*light[2])
+	for idx := range light {
+		light[idx] /= lightLen
+	}
+
+	pixels := make([]byte, hf.cols*hf.rows*4)
+
+	for row := range hf.rows {
+		for col := range hf.cols {
+			gradient := hf.Gradient(hf.posOf(col, row)).Mulf(heightScale * exaggeration)
+
+			// normal of the surface
+			normalLen := math.Sqrt(gradient.X*gradient.X + gradient.Y*gradient.Y + 1)
+			shade := (-gradient.X*light[0] - gradient.Y*light[1] + light[2]) / normalLen
+
+			// difference to the shading of flat land
+			diff := shade - light[2]
+			alpha := Clamp(math.Abs(diff)*strength, 0, 0.25)
+
+			// white for light, dark brown for shadows, premultiplied alpha
+			tint := iff(diff > 0, [3]float64{1, 1, 1}, [3]float64{0.35, 0.28, 0.2})
+
+			idx := (row*hf.cols + col) * 4
+			pixels[idx+0] = uint8(tint[0] * alpha * 0xff)
+			pixels[idx+1] = uint8(tint[1] * alpha * 0xff)
+			pixels[idx+2] = uint8(tint[2] * alpha * 0xff)
+			pixels[idx+3] = uint8(alpha * 0xff)
+		}
+	}
+
+	return pixels
+}
+
+// ShadeImage returns the hillshading and the area of the world it covers
+func (hf *heightField) ShadeImage() (*image.RGBA, Rect) {
+	img := &image.RGBA{
+		Pix:    hf.shadePixels,
+		Stride: hf.cols * 4,
+		Rect:   image.Rect(0, 0, hf.cols, hf.rows),
+	}
+
+	// each pixel is centered on its sample
+	origin := hf.world.Min.Sub(vecSplat(heightCellSize / 2))
+	size := Vec{X: float64(hf.cols), Y: float64(hf.rows)}.Mulf(heightCellSize)
+
+	return img, Rect{Min: origin, Max: origin.Add(size)}
+}
+
+// Contours traces the lines of equal height using marching squares
+func (hf *heightField) Contours(levels int) []Line {
+	var lines []Line
+
+	for row := range hf.rows - 1 {
+		for col := range hf.cols - 1 {
+			corners := [4]Vec{
+				hf.posOf(col, row),
+				hf.posOf(col+1, row),
+				hf.posOf(col+1, row+1),
+				hf.posOf(col, row+1),
+			}
+
+			heights := [4]float64{
+				hf.heightOf(col, row),
+				hf.heightOf(col+1, row),
+				hf.heightOf(col+1, row+1),
+				hf.heightOf(col, row+1),
+			}
+
+			for level := 1; level < levels; level++ {
+				height := float64(level) / float64(levels)
+
+				// points where the contour crosses the edges of the cell
+				var crossings []Vec
+				for idx := range 4 {
+					next := (idx + 1) % 4
+
+					a, b := heights[idx], heights[next]
+					if (a < height) == (b < height) {
+						continue
+					}
+
+					t := (height - a) / (b - a)
+					crossings = append(crossings, corners[idx].Add(corners[next].Sub(corners[idx]).Mulf(t)))
+				}
+
+				// two crossings are a simple line, four are a saddle
+				for idx := 0; idx+1 < len(crossings); idx += 2 {
+					lines = append(lines, Line{Start: crossings[idx], End: crossings[idx+1]})
+				}
+			}
+		}
+	}
+
+	return lines
+}
+
+// GenerateContours creates the meshes of the contour lines
+func (t *TerrainGenerator) GenerateContours() {
+	const levels = 12
+	const width = 12
+
+	var mesh TerrainMesh
+
+	for _, line := range t.terrain.heights.Contours(levels) {
+		// indices are 16 bit, start a new mesh before we run out
+		if len(mesh.Vertices) > math.MaxUint16-8 {
+			t.finishContours(&mesh)
+		}
+
+		dir := line.End.Sub(line.Start).Normalized()
+		normal := dir.Rotated(math.Pi / 2).Mulf(width / 2)
+
+		// overlap a little so the lines have no gaps
+		start := line.Start.Sub(dir.Mulf(width / 2))
+		end := line.End.Add(dir.Mulf(width / 2))
+
+		mesh.appendQuad(start.Add(normal), end.Add(normal), end.Sub(normal), start.Sub(normal))
+	}
+
+	t.finishContours(&mesh)
+}
+
+func (t *TerrainGenerator) finishContours(mesh *TerrainMesh) {
+	if len(mesh.Vertices) == 0 {
+		return
+	}
+
+	ApplyColorToVertices(mesh.Vertices, ContourColor)
+	t.terrain.Contours = append(t.terrain.Contours, *mesh)
+	*mesh = TerrainMesh{}
+}
+
+// HeightAt returns the elevation at the given point in [0, 1]
+func (t *Terrain) HeightAt(pos Vec) float64 {
+	if t.heights == nil {
+		return 0
+	}
+
+	return t.heights.At(pos)
+}
+
+// ElevationAt returns the elevation at the given point in meters
+func (t *Terrain) ElevationAt(pos Vec) float64 {
+	return t.HeightAt(pos) * heightScale
+}
@@ -0,0 +1,390 @@
+package main
+
+import (
+	"github.com/furui/fastnoiselite-go"
+	. "github.com/quasilyte/gmath"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"sync"
+)
+
+// TerrainMesh is a layer of the terrain drawn as triangles in world space
+type TerrainMesh struct {
+	Vertices []Vertex
+	Indices  []uint16
+}
+
+// appendFan adds a polygon that is star shaped around its center
+func (m *TerrainMesh) appendFan(center Vec, outline []Vec) {
+	base := uint16(len(m.Vertices))
+
+	m.Vertices = append(m.Vertices, vertexOf(center))
+	for _, point := range outline {
+		m.Vertices = append(m.Vertices, vertexOf(point))
+	}
+
+	for idx := range outline {
+		next := (idx + 1) % len(outline)
+		m.Indices = append(m.Indices, base, base+1+uint16(idx), base+1+uint16(next))
+	}
+}
+
+func (m *TerrainMesh) appendQuad(a, b, c, d Vec) {
+	base := uint16(len(m.Vertices))
+
+	m.Vertices = append(m.Vertices, vertexOf(a), vertexOf(b), vertexOf(c), vertexOf(d))
+	m.Indices = append(m.Indices, base, base+1, base+2, base, base+2, base+3)
+}
+
+func vertexOf(pos Vec) Vertex {
+	return Vertex{DstX: float32(pos.X), DstY: float32(pos.Y)}
+}
+
+// flags in the water mask
+const (
+	// streets may cross rivers using a bridge
+	waterRiver uint8 = 1 << iota
+
+	// lakes and the sea are never crossed by streets
+	waterStill
+)
+
+// waterMask rasterizes all water of the terrain for quick lookups
+type waterMask struct {
+	world    Rect
+	cellSize float64
+	cols     int
+	rows     int
+	cells    []uint8
+}
+
+func newWaterMask(world Rect) *waterMask {
+	const cellSize = 25
+
+	cols := int(math.Ceil(world.Width() / cellSize))
+	rows := int(math.Ceil(world.Height() / cellSize))
+
+	return &waterMask{
+		world:    world,
+		cellSize: cellSize,
+		cols:     cols,
+		rows:     rows,
+		cells:    make([]uint8, cols*rows),
+	}
+}
+
+func (m *waterMask) At(pos Vec) uint8 {
+	col := int((pos.X - m.world.Min.X) / m.cellSize)
+	row := int((pos.Y - m.world.Min.Y) / m.cellSize)
+
+	if col < 0 || row < 0 || col >= m.cols || row >= m.rows {
+		return 0
+	}
+
+	return m.cells[row*m.cols+col]
+}
+
+// clear removes the flag from all cells
+func (m *waterMask) clear(flag uint8) {
+	for idx := range m.cells {
+		m.cells[idx] &^= flag
+	}
+}
+
+func (m *waterMask) clone() *waterMask {
+	clone := *m
+	clone.cells = slices.Clone(m.cells)
+	return &clone
+}
+
+// fill marks all cells whose center is covered by one of the triangles
+func (m *waterMask) fill(vertices []Vertex, indices []uint16, flag uint8) {
+	vecOf := func(vertex Vertex) Vec {
+		return Vec{X: float64(vertex.DstX), Y: float64(vertex.DstY)}
+	}
+
+	for idx := 0; idx+2 < len(indices); idx += 3 {
+		a := vecOf(vertices[indices[idx]])
+		b := vecOf(vertices[indices[idx+1]])
+		c := vecOf(vertices[indices[idx+2]])
+
+		bbox := bboxOf([]Vec{a, b, c})
+
+		colMin := max(0, int((bbox.Min.X-m.world.Min.X)/m.cellSize))
+		colMax := min(m.cols-1, int((bbox.Max.X-m.world.Min.X)/m.cellSize))
+		rowMin := max(0, int((bbox.Min.Y-m.world.Min.Y)/m.cellSize))
+		rowMax := min(m.rows-1, int((bbox.Max.Y-m.world.Min.Y)/m.cellSize))
+
+		for row := rowMin; row <= rowMax; row++ {
+			for col := colMin; col <= colMax; col++ {
+				center := m.world.Min.Add(Vec{X: (float64(col) + 0.5) * m.cellSize, Y: (float64(row) + 0.5) * m.cellSize})
+				if PointInTriangle(a, b, c, center) {
+					m.cells[row*m.cols+col] |= flag
+				}
+			}
+		}
+	}
+}
+
+// newLayerNoise creates a smooth noise returning values in [-1, 1]
+func newLayerNoise(rng *rand.Rand, frequency float64) *fastnoiselite.FastNoiseLite {
+	noise := fastnoiselite.NewNoise()
+	noise.SetNoiseType(fastnoiselite.NoiseTypeOpenSimplex2)
+	noise.Seed = rng.Int32()
+	noise.Frequency = frequency
+
+	return noise
+}
+
+func sampleNoise01(noise *fastnoiselite.FastNoiseLite, pos Vec) float64 {
+	value := noise.GetNoise2D(fastnoiselite.FNLfloat(pos.X), fastnoiselite.FNLfloat(pos.Y))
+	return Clamp((value+1)/2, 0, 1)
+}
+
+// IsForest checks if the given point lies within a forest
+func (t *Terrain) IsForest(pos Vec) bool {
+	if t.forest == nil {
+		return false
+	}
+
+	return sampleNoise01(t.forest, pos) > t.forestThreshold && !t.IsWater(pos)
+}
+
+// IsWater checks if the given point lies within a river, a lake or the sea
+func (t *Terrain) IsWater(pos Vec) bool {
+	return t.water != nil && t.water.At(pos) != 0
+}
+
+// IsStillWater checks if the given point lies within a lake or the sea
+func (t *Terrain) IsStillWater(pos Vec) bool {
+	return t.water != nil && t.water.At(pos)&waterStill != 0
+}
+
+// GenerateCoast puts the sea along one side of the world
+func (t *TerrainGenerator) GenerateCoast() {
+	const sampleDistance = 200
+
+	side := t.rng.IntN(4)
+
+	// depth of the sea, measured from the edge of the world
+	depthAt := func(offset float64) float64 {
+		const depth = 2_000
+		const variation = 1_500
+
+		wave := t.terrain.elevation.GetNoise2D(fastnoiselite.FNLfloat(offset), fastnoiselite.FNLfloat(-10_000))
+		return depth + variation*float64(wave)
+	}
+
+	// length of the edge and the direction into the world
+	length := iff(side%2 == 0, t.world.Width(), t.world.Height())
+
+	pointAt := func(offset, depth float64) Vec {
+		switch side {
+		case 0: // north
+			return Vec{X: t.world.Min.X + offset, Y: t.world.Min.Y + depth}
+		case 1: // east
+			return Vec{X: t.world.Max.X - depth, Y: t.world.Min.Y + offset}
+		case 2: // south
+			return Vec{X: t.world.Min.X + offset, Y: t.world.Max.Y - depth}
+		default: // west
+			return Vec{X: t.world.Min.X + depth, Y: t.world.Min.Y + offset}
+		}
+	}
+
+	var mesh TerrainMesh
+
+	// reach a little outside the world, so we do not see the edge of the sea
+	const overscan = 1_000
+
+	for offset := float64(-overscan); offset < length+overscan; offset += sampleDistance {
+		next := offset + sampleDistance
+
+		mesh.appendQuad(
+			pointAt(offset, -overscan),
+			pointAt(next, -overscan),
+			pointAt(next, depthAt(next)),
+			pointAt(offset, depthAt(offset)),
+		)
+	}
+
+	ApplyColorToVertices(mesh.Vertices, WaterColor)
+
+	t.terrain.water.fill(mesh.Vertices, mesh.Indices, waterStill)
+	t.terrain.Sea = mesh
+}
+
+// GenerateLake puts a lake into a valley of the world
+func (t *TerrainGenerator) GenerateLake() {
+	const pointCount = 32
+
+	var center Vec
+	var radius float64
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 32 {
+			// no space left for a lake
+			return
+		}
+
+		// lakes form in low places, take the lowest of a few candidates
+		center, _, _ = MaxOf(
+			Repeat(8, func() Vec { return RandVecIn(t.rng, t.world) }),
+			func(pos Vec) float64 { return -t.terrain.HeightAt(pos) },
+		)
+
+		radius = Randf(t.rng, 500.0, 1_200.0)
+
+		// keep some distance to other water, lakes must not merge into rivers
+		var blocked bool
+		for idx := range pointCount {
+			probe := center.Add(Vec{X: radius * 1.5}.Rotated(Rad(idx) * 2 * math.Pi / pointCount))
+			blocked = blocked || t.terrain.IsWater(probe)
+		}
+
+		if !blocked && !t.terrain.IsWater(center) {
+			break
+		}
+	}
+
+	var outline []Vec
+	for idx := range pointCount {
+		angle := Rad(idx) * 2 * math.Pi / pointCount
+
+		// vary the radius smoothly along the shore
+		probe := center.Add(Vec{X: radius}.Rotated(angle))
+		scale := 0.6 + 0.6*sampleNoise01(t.terrain.elevation, probe.Mulf(4))
+
+		outline = append(outline, center.Add(Vec{X: radius * scale}.Rotated(angle)))
+	}
+
+	var lake TerrainMesh
+	lake.appendFan(center, outline)
+	ApplyColorToVertices(lake.Vertices, WaterColor)
+
+	t.terrain.water.fill(lake.Vertices, lake.Indices, waterStill)
+	t.terrain.Lakes = append(t.terrain.Lakes, lake)
+}
+
+// GenerateForests places trees wherever the forest noise is high enough.
+// Must be called after all water has been generated.
+func (t *TerrainGenerator) GenerateForests() {
+	const treeDistance = 200
+	const treeRadius = 70
+
+	// trees get their own rng, the number of trees must not change the rest of the level
+	rng := RandWithSeed(t.rng.Uint64())
+
+	var mesh TerrainMesh
+
+	for y := t.world.Min.Y; y < t.world.Max.Y; y += treeDistance {
+		for x := t.world.Min.X; x < t.world.Max.X; x += treeDistance {
+			pos := Vec{X: x, Y: y}.Add(Vec{X: Randf(rng, -60.0, 60.0), Y: Randf(rng, -60.0, 60.0)})
+			if !t.terrain.IsForest(pos) {
+				continue
+			}
+
+			// indices are 16 bit, start a new mesh before we run out
+			if len(mesh.Vertices) > math.MaxUint16-16 {
+				t.finishForest(&mesh)
+			}
+
+			radius := treeRadius * Randf(rng, 0.7, 1.3)
+
+			var outline []Vec
+			for idx := range 6 {
+				outline = append(outline, pos.Add(Vec{X: radius}.Rotated(Rad(idx)*math.Pi/3)))
+			}
+
+			mesh.appendFan(pos, outline)
+		}
+	}
+
+	t.finishForest(&mesh)
+}
+
+func (t *TerrainGenerator) finishForest(mesh *TerrainMesh) {
+	if len(mesh.Vertices) == 0 {
+		return
+	}
+
+	ApplyColorToVertices(mesh.Vertices, ForestColor)
+	t.terrain.Forests = append(t.terrain.Forests, *mesh)
+	*mesh = TerrainMesh{}
+}
+
+// trackCosts caches the terrain factor of the price of a track
+type trackCosts struct {
+	mu      sync.Mutex
+	factors map[[2]Vec]float64
+}
+
+// TrackCostFactor returns how much more expensive a track between a and b is
+// compared to a track over flat open land, e.g. because of bridges, forests or slopes
+func (t *Terrain) TrackCostFactor(a, b Vec) float64 {
+	if t.costs == nil {
+		return 1
+	}
+
+	// the factor is the same in both directions
+	key := [2]Vec{a, b}
+	if b.X < a.X || b.X == a.X && b.Y < a.Y {
+		key = [2]Vec{b, a}
+	}
+
+	t.costs.mu.Lock()
+	defer t.costs.mu.Unlock()
+
+	if factor, ok := t.costs.factors[key]; ok {
+		return factor
+	}
+
+	factor := t.trackCostFactor(key[0], key[1])
+	t.costs.factors[key] = factor
+	return factor
+}
+
+func (t *Terrain) trackCostFactor(a, b Vec) float64 {
+	const sampleDistance = 100
+
+	const bridgeFactor = 3.0
+	const forestFactor = 1.3
+
+	// extra cost per meter of climb per meter of track
+	const gradientFactor = 10.0
+
+	// trains can not climb steeper slopes, we need a tunnel or a cutting
+	const maxGradient = 0.05
+	const tunnelFactor = 2.5
+
+	length := a.DistanceTo(b)
+	if length == 0 {
+		return 1
+	}
+
+	steps := max(1, int(length/sampleDistance))
+	stepLength := length / float64(steps)
+
+	pointAt := func(f float64) Vec {
+		return a.Add(b.Sub(a).Mulf(f / float64(steps)))
+	}
+
+	var sum float64
+	for idx := range steps {
+		start, end := pointAt(float64(idx)), pointAt(float64(idx+1))
+		pos := pointAt(float64(idx) + 0.5)
+
+		gradient := math.Abs(t.ElevationAt(end)-t.ElevationAt(start)) / stepLength
+
+		switch {
+		case t.IsWater(pos):
+			sum += bridgeFactor
+		case gradient > maxGradient:
+			sum += tunnelFactor
+		default:
+			sum += (1 + gradient*gradientFactor) * iff(t.IsForest(pos), forestFactor, 1.0)
+		}
+	}
+
+	return sum / float64(steps)
+}
@@ -0,0 +1,395 @@
+package main
+
+import (
+	. "github.com/quasilyte/gmath"
+	"math"
+	"slices"
+)
+
+// riverCourse is the center line of a river from its source to its mouth,
+// with the width of the river at each point
+type riverCourse struct {
+	Points []Vec
+	Widths []float64
+}
+
+// distance between two points of a river course after meandering
+const riverPointSpacing = 80
+
+// widthsAlong lets the width of a river grow linearly from source to mouth
+func widthsAlong(points []Vec, source, mouth float64) []float64 {
+	total := pathLength(points)
+
+	widths := make([]float64, len(points))
+
+	var distance float64
+	for idx := range points {
+		if idx > 0 {
+			distance += points[idx].DistanceTo(points[idx-1])
+		}
+
+		widths[idx] = source + (mouth-source)*distance/max(total, 1)
+	}
+
+	return widths
+}
+
+func pathLength(points []Vec) float64 {
+	var length float64
+	for idx := 1; idx < len(points); idx++ {
+		length += points[idx].DistanceTo(points[idx-1])
+	}
+
+	return length
+}
+
+// meander subdivides the course and lets it swing from side to side.
+// Bends are wider in the lowlands. Both ends stay in place.
+func (t *TerrainGenerator) meander(points []Vec, amplitude float64) []Vec {
+	const wavelength = 2_000
+
+	var dense []Vec
+	for idx := 0; idx+1 < len(points); idx++ {
+		a, b := points[idx], points[idx+1]
+
+		steps := max(1, int(math.Ceil(a.DistanceTo(b)/riverPointSpacing)))
+		for step := range steps {
+			dense = append(dense, a.LinearInterpolate(b, float64(step)/float64(steps)))
+		}
+	}
+
+	dense = append(dense, points[len(points)-1])
+
+	total := pathLength(dense)
+
+	phase := Randf(t.rng, 0, 2*math.Pi)
+
+	result := make([]Vec, len(dense))
+
+	var distance float64
+	for idx, point := range dense {
+		if idx > 0 {
+			step := point.DistanceTo(dense[idx-1])
+			distance += step
+
+			// vary the length of the bends a little
+			phase += step * 2 * math.Pi / wavelength * (0.6 + 0.8*sampleNoise01(t.terrain.elevation, point.Mulf(2)))
+		}
+
+		tangent := dense[min(idx+1, len(dense)-1)].Sub(dense[max(idx-1, 0)]).Normalized()
+		normal := tangent.Rotated(math.Pi / 2)
+
+		// fade in and out, the ends must connect to other rivers
+		envelope := Clamp(min(distance, total-distance)/wavelength, 0, 1)
+		scale := 1.2 - t.terrain.HeightAt(point)
+
+		result[idx] = point.Add(normal.Mulf(amplitude * envelope * scale * math.Sin(phase)))
+	}
+
+	return result
+}
+
+// truncateAtRivers stops the course where it first runs into one of the rivers.
+// Returns true if the course was cut.
+func truncateAtRivers(points []Vec, rivers []River) ([]Vec, bool) {
+	for idx := 0; idx+1 < len(points); idx++ {
+		line := Line{Start: points[idx], End: points[idx+1]}
+
+		for _, river := range rivers {
+			for _, other := range river.Lines {
+				if intersection, ok := other.Intersection(line); ok {
+					return append(points[:idx+1:idx+1], intersection), true
+				}
+			}
+		}
+	}
+
+	return points, false
+}
+
+// tributaries generates the rivers flowing into the given one. Each tributary
+// widens the main river below its confluence.
+func (t *TerrainGenerator) tributaries(main *riverCourse, others []River) []riverCourse {
+	var tributaries []riverCourse
+
+	// tributaries must not cross each other either
+	others = slices.Clone(others)
+
+	count := t.rng.IntN(t.config.TributaryCount + 1)
+
+	for range count {
+		// only join where the main river is visible
+		var visible []int
+		for idx, point := range main.Points {
+			if t.world.Contains(point) {
+				visible = append(visible, idx)
+			}
+		}
+
+		if len(visible) < 10 {
+			break
+		}
+
+		confluenceIdx := visible[len(visible)/5+t.rng.IntN(len(visible)*3/5)]
+		confluence := main.Points[confluenceIdx]
+
+		// tributaries join at an angle, coming from upstream
+		upstream := main.Points[max(confluenceIdx-1, 0)].Sub(main.Points[min(confluenceIdx+1, len(main.Points)-1)]).Normalized()
+		angle := Randf(t.rng, DegToRad(30), DegToRad(70)) * iff(prob(t.rng, 0.5), Rad(1), -1)
+
+		source := confluence.Add(upstream.Rotated(angle).Mulf(Randf(t.rng, 3_000.0, 7_000.0)))
+		if t.terrain.IsWater(source) {
+			continue
+		}
+
+		points := t.meander(t.tributaryPath(source, confluence), 120)
+
+		// a tributary might cross another river before reaching the confluence
+		points, _ = truncateAtRivers(points, others)
+		others = append(others, River{Lines: vecsToLines(points)})
+
+		// the tributary is a little narrower than the main river at the confluence
+		mainWidth := main.Widths[confluenceIdx]
+		width := Randf(t.rng, 0.4, 0.6) * mainWidth
+
+		tributaries = append(tributaries, riverCourse{
+			Points: points,
+			Widths: widthsAlong(points, min(60, width), width),
+		})
+
+		// the joined water widens the main river
+		end := points[len(points)-1]
+
+		var nearestIdx int
+		for idx, point := range main.Points {
+			if point.DistanceSquaredTo(end) < main.Points[nearestIdx].DistanceSquaredTo(end) {
+				nearestIdx = idx
+			}
+		}
+
+		if end.DistanceTo(main.Points[nearestIdx]) > main.Widths[nearestIdx] {
+			// joined a different river
+			continue
+		}
+
+		for idx := nearestIdx + 1; idx < len(main.Widths); idx++ {
+			main.Widths[idx] += 0.3 * width
+		}
+	}
+
+	return tributaries
+}
+
+// tributaryPath flows downhill from the source, but always makes its way to the confluence
+func (t *TerrainGenerator) tributaryPath(source, confluence Vec) []Vec {
+	const stepSize = 160
+
+	points := []Vec{source}
+
+	pos := source
+	dir := directionTo(source, confluence)
+
+	for range 200 {
+		if pos.DistanceTo(confluence) < 2*stepSize {
+			break
+		}
+
+		downhill := t.terrain.heights.Gradient(pos).Neg()
+		if !downhill.IsZero() {
+			downhill = downhill.Normalized()
+		}
+
+		// smooth the direction, rivers do not make sharp turns
+		want := directionTo(pos, confluence).Add(downhill.Mulf(0.6)).Normalized()
+		dir = dir.Mulf(2).Add(want).Normalized()
+
+		pos = pos.Add(dir.Mulf(stepSize))
+		points = append(points, pos)
+	}
+
+	return append(points, confluence)
+}
+
+// mouth adds a delta or an estuary where the river leaves the world
+func (t *TerrainGenerator) mouth(main *riverCourse, others []River) []riverCourse {
+	// the last point inside the world
+	exitIdx := -1
+	for idx, point := range main.Points {
+		if t.world.Contains(point) {
+			exitIdx = idx
+		}
+	}
+
+	if exitIdx < 0 {
+		return nil
+	}
+
+	switch {
+	case prob(t.rng, t.config.DeltaChance):
+		return t.delta(main, exitIdx, others)
+
+	case prob(t.rng, t.config.EstuaryChance):
+		t.estuary(main, exitIdx)
+	}
+
+	return nil
+}
+
+// delta splits the river into a few branches before it leaves the world
+func (t *TerrainGenerator) delta(main *riverCourse, exitIdx int, others []River) []riverCourse {
+	const deltaLength = 3_000
+	const stepSize = 160
+
+	// walk back from the exit to find the point where the river splits
+	splitIdx := exitIdx
+	for distance := 0.0; splitIdx > 0 && distance < deltaLength; splitIdx-- {
+		distance += main.Points[splitIdx].DistanceTo(main.Points[splitIdx-1])
+	}
+
+	if splitIdx == 0 {
+		// the river is too short for a delta
+		return nil
+	}
+
+	split := main.Points[splitIdx]
+	flow := main.Points[splitIdx+1].Sub(main.Points[splitIdx-1]).Normalized()
+
+	outer := Rect{
+		Min: t.world.Min.Sub(vecSplat(2_000)),
+		Max: t.world.Max.Add(vecSplat(2_000)),
+	}
+
+	var branches []riverCourse
+
+	count := 1 + t.rng.IntN(2)
+	for idx := range count {
+		// spread the branches to both sides of the main river
+		side := iff(idx%2 == 0, Rad(1), -1)
+		dir := flow.Rotated(side * Randf(t.rng, DegToRad(20), DegToRad(40)))
+
+		points := []Vec{split}
+
+		// branches run straight to the edge, meandering adds the bends
+		pos := split
+		for len(points) < 400 && outer.Contains(pos) {
+			pos = pos.Add(dir.Mulf(stepSize))
+			points = append(points, pos)
+		}
+
+		points = t.meander(points, 80)
+		points, _ = truncateAtRivers(points, others)
+
+		width := main.Widths[splitIdx] * Randf(t.rng, 0.5, 0.7)
+
+		branches = append(branches, riverCourse{
+			Points: points,
+			Widths: widthsAlong(points, width, width*1.2),
+		})
+	}
+
+	return branches
+}
+
+// estuary lets the river open up towards the edge of the world
+func (t *TerrainGenerator) estuary(main *riverCourse, exitIdx int) {
+	const estuaryLength = 2_500
+	const flare = 2.5
+
+	var distance float64
+	for idx := exitIdx; idx > 0 && distance < estuaryLength; idx-- {
+		distance += main.Points[idx].DistanceTo(main.Points[idx-1])
+
+		f := 1 - distance/estuaryLength
+		main.Widths[idx] *= 1 + (flare-1)*f*f
+	}
+
+	// keep the full width until the river is out of sight
+	for idx := exitIdx + 1; idx < len(main.Widths); idx++ {
+		main.Widths[idx] = main.Widths[exitIdx]
+	}
+}
+
+// addRiver creates the mesh of the river and marks it in the water mask
+func (t *TerrainGenerator) addRiver(course riverCourse) {
+	t.terrain.addRiver(course)
+}
+
+func (t *Terrain) addRiver(course riverCourse) {
+	if len(course.Points) < 2 {
+		return
+	}
+
+	mesh, outline := riverMesh(course)
+
+	ApplyColorToVertices(mesh.Vertices, WaterColor)
+
+	t.water.fill(mesh.Vertices, mesh.Indices, waterRiver)
+
+	t.Rivers = append(t.Rivers, River{
+		Lines:       vecsToLines(course.Points),
+		Vertices:    mesh.Vertices,
+		Indices:     mesh.Indices,
+		Outline:     outline,
+		OutlineGrid: NewGrid(vecSplat(50), outline),
+	})
+}
+
+// removeRivers deletes the rivers with the given indices and clears their water
+func (t *Terrain) removeRivers(indices []int) {
+	var rivers []River
+	for idx, river := range t.Rivers {
+		if !slices.Contains(indices, idx) {
+			rivers = append(rivers, river)
+		}
+	}
+
+	t.Rivers = rivers
+
+	t.water.clear(waterRiver)
+	for _, river := range t.Rivers {
+		t.water.fill(river.Vertices, river.Indices, waterRiver)
+	}
+}
+
+// riverMesh builds a strip of triangles along the course, with a round source.
+// The outline consists of both banks and the ends of the river.
+func riverMesh(course riverCourse) (TerrainMesh, []Line) {
+	points := course.Points
+
+	var left, right []Vec
+	for idx, point := range points {
+		tangent := points[min(idx+1, len(points)-1)].Sub(points[max(idx-1, 0)]).Normalized()
+		normal := tangent.Rotated(math.Pi / 2).Mulf(course.Widths[idx] / 2)
+
+		left = append(left, point.Add(normal))
+		right = append(right, point.Sub(normal))
+	}
+
+	var mesh TerrainMesh
+
+	for idx := range points {
+		mesh.Vertices = append(mesh.Vertices, vertexOf(left[idx]), vertexOf(right[idx]))
+	}
+
+	for idx := 0; idx+1 < len(points); idx++ {
+		l0, r0 := uint16(2*idx), uint16(2*idx+1)
+		l1, r1 := l0+2, r0+2
+		mesh.Indices = append(mesh.Indices, l0, l1, r1, l0, r1, r0)
+	}
+
+	// half a circle around the source, from the right to the left bank
+	var source []Vec
+	for step := range 9 {
+		source = append(source, points[0].Add(right[0].Sub(points[0]).Rotated(-Rad(step)*math.Pi/8)))
+	}
+
+	mesh.appendFan(points[0], source)
+
+	var outline []Line
+	outline = append(outline, vecsToLines(left)...)
+	outline = append(outline, vecsToLines(right)...)
+	outline = append(outline, vecsToLines(source)...)
+	outline = append(outline, Line{Start: left[len(left)-1], End: right[len(right)-1]})
+
+	return mesh, outline
+}
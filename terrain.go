@@ -2,8 +2,6 @@ package main
 
 import (
 	"github.com/furui/fastnoiselite-go"
-	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/vector"
 	. "github.com/quasilyte/gmath"
 	"iter"
 	"math/rand/v2"
@@ -12,13 +10,31 @@ import (
 
 type Terrain struct {
 	Rivers []River
+	Lakes  []TerrainMesh
 
-	scratch []ebiten.Vertex
+	// the sea along one side of the world, empty on most maps
+	Sea TerrainMesh
+
+	Forests []TerrainMesh
+
+	// lines of equal height, drawn below everything else
+	Contours []TerrainMesh
+
+	elevation       *fastnoiselite.FastNoiseLite
+	forest          *fastnoiselite.FastNoiseLite
+	forestThreshold float64
+
+	// shared by all copies of the terrain
+	heights *heightField
+	water   *waterMask
+	costs   *trackCosts
+
+	scratch []Vertex
 }
 
 type River struct {
 	Lines    []Line
-	Vertices []ebiten.Vertex
+	Vertices []Vertex
 	Indices  []uint16
 
 	Outline     []Line
@@ -26,119 +42,110 @@ type River struct {
 }
 
 type TerrainGenerator struct {
-	noise *fastnoiselite.FastNoiseLite
-	rng   *rand.Rand
+	noise  *fastnoiselite.FastNoiseLite
+	rng    *rand.Rand
+	config GenerationConfig
 
 	// clip rect of the world. No need to generate outside of the world
 	world Rect
 
 	// noise put into an image after generating
-	debugNoiseImage *ebiten.Image
+	debugNoiseImage *Image
 
 	// the generated terrain
 	terrain Terrain
 }
 
-func NewTerrainGenerator(rng *rand.Rand, worldSize Rect) *TerrainGenerator {
+func NewTerrainGenerator(rng *rand.Rand, worldSize Rect, config GenerationConfig) *TerrainGenerator {
 	noise := fastnoiselite.NewNoise()
 	noise.Seed = rng.Int32()
-	noise.Frequency = 0.0001
+	noise.Frequency = config.TerrainFrequency
+
+	// the other layers use their own noise
+	elevation := newLayerNoise(rng, 0.00015)
+	forest := newLayerNoise(rng, 0.0003)
 
 	return &TerrainGenerator{
-		noise: noise,
-		rng:   rng,
-		world: worldSize,
+		noise:  noise,
+		rng:    rng,
+		config: config,
+		world:  worldSize,
+		terrain: Terrain{
+			elevation:       elevation,
+			heights:         newHeightField(worldSize, noise, elevation),
+			forest:          forest,
+			forestThreshold: 0.9 - config.ForestCoverage,
+			water:           newWaterMask(worldSize),
+			costs:           &trackCosts{factors: map[[2]Vec]float64{}},
+		},
 	}
 }
 
-func (t *Terrain) Draw(target *ebiten.Image, toScreen ebiten.GeoM) {
-	var top ebiten.DrawTrianglesOptions
-	top.AntiAlias = true
+// Clone copies the terrain, rivers of the copy can be changed without touching the original
+func (t *Terrain) Clone() Terrain {
+	clone := *t
+	clone.Rivers = slices.Clone(t.Rivers)
+	clone.scratch = nil
 
-	for _, river := range t.Rivers {
-		// bring vertices to screen
-		t.scratch = TransformVertices(toScreen, river.Vertices, t.scratch[:0])
-		target.DrawTriangles(t.scratch, river.Indices, whiteImage, &top)
+	if t.water != nil {
+		clone.water = t.water.clone()
 	}
-}
 
-func (t *TerrainGenerator) DebugDraw(target *ebiten.Image, toScreen ebiten.GeoM) {
-	if t.debugNoiseImage == nil {
-		toWorld := toScreen
-		toWorld.Invert()
+	// prices depend on the water
+	clone.costs = &trackCosts{factors: map[[2]Vec]float64{}}
 
-		t.debugNoiseImage = noiseToImage(t.noise, target.Bounds().Dx(), target.Bounds().Dy(), toWorld)
-	}
-
-	target.DrawImage(t.debugNoiseImage, nil)
+	return clone
 }
 
 func (t *TerrainGenerator) Terrain() Terrain {
 	return t.terrain
 }
 
+// GenerateRiver generates a river flowing through the world, including its
+// tributaries and its mouth. A river ends where it runs into an existing river.
+// No river is added if the terrain has no valley to follow, e.g. if it is flat.
 func (t *TerrainGenerator) GenerateRiver() {
 	var lines []Line
 
-	for {
-		// generate a valid river path
-		lines = t.riverCandidate()
-		if lines == nil {
-			continue
-		}
-
-		// check if we intersect another river
-		for _, river := range t.terrain.Rivers {
-		lines:
-			for idx, line := range lines {
-				for _, intersectionCandidate := range river.Lines {
-					intersection, ok := intersectionCandidate.Intersection(line)
-					if !ok {
-						continue
-					}
-
-					// we got an intersection with a different river. shorten the current
-					// line segment and stop the new river here
-					lines[idx].End = intersection
-					lines = lines[:idx]
-					break lines
-				}
-			}
+	for attempt := 0; lines == nil; attempt++ {
+		if attempt >= 100 {
+			// no valid river path in this terrain
+			return
 		}
 
-		break
+		// generate a valid river path
+		lines = t.riverCandidate()
 	}
 
-	// create path from river lines
-	path := pathOf(linesToVecs(lines), false)
+	points := t.meander(linesToVecs(lines), 250)
 
-	// now we have a river, create a path from it that has a given width
-	width := Randf[float32](t.rng, float32(300), 600)
+	// check if we intersect another river and stop the new river there
+	points, joined := truncateAtRivers(points, t.terrain.Rivers)
 
-	// and generate vertices in world space
-	vertices, indices := path.AppendVerticesAndIndicesForStroke(nil, nil, &vector.StrokeOptions{
-		Width:    width,
-		LineJoin: vector.LineJoinRound,
-	})
+	// the river grows wider downstream
+	mouthWidth := Randf(t.rng, 300.0, 600.0)
 
-	ApplyColorToVertices(vertices, WaterColor)
+	main := riverCourse{
+		Points: points,
+		Widths: widthsAlong(points, mouthWidth*0.3, mouthWidth),
+	}
 
-	outline := verticesToLines(vertices, indices)
+	courses := t.tributaries(&main, t.terrain.Rivers)
 
-	river := River{
-		Lines:       lines,
-		Vertices:    vertices,
-		Indices:     indices,
-		Outline:     outline,
-		OutlineGrid: NewGrid(vecSplat(50), outline),
+	if !joined {
+		courses = append(courses, t.mouth(&main, t.terrain.Rivers)...)
 	}
 
-	t.terrain.Rivers = append(t.terrain.Rivers, river)
+	t.addRiver(main)
+
+	for _, course := range courses {
+		t.addRiver(course)
+	}
 }
 
 func (t *TerrainGenerator) riverCandidate() []Line {
 	stepSize := t.world.Width() / 100
-	pointsIter := walk(t.rng, t.noise, t.world, stepSize)
+	pointsIter := walk(t.rng, t.terrain.HeightAt, t.world, stepSize)
 
 	var points []Vec
 	var insideCount int
@@ -168,12 +175,16 @@ func (t *TerrainGenerator) riverCandidate() []Line {
 		return nil
 	}
 
+	// rivers flow downhill, the mouth must be lower than the source
+	if t.terrain.HeightAt(points[len(points)-1]) >= t.terrain.HeightAt(points[0]) {
+		return nil
+	}
+
 	return vecsToLines(points)
 }
 
-func walk(rng *rand.Rand, noise *fastnoiselite.FastNoiseLite, world Rect, stepSize float64) iter.Seq[Vec] {
-	type F = fastnoiselite.FNLfloat
-
+// walk follows the valleys of the height field, starting on high ground outside of the world
+func walk(rng *rand.Rand, heightAt func(Vec) float64, world Rect, stepSize float64) iter.Seq[Vec] {
 	return func(yield func(Vec) bool) {
 		// increase rectangle size slightly
 		outer := Rect{
@@ -181,8 +192,8 @@ func walk(rng *rand.Rand, noise *fastnoiselite.FastNoiseLite, world Rect, stepSi
 			Max: world.Max.Add(world.Size().Mulf(0.1)),
 		}
 
-		// find a starting point that is in outer, but not in world
-		pos := rectStart(rng, outer, world)
+		// find a starting point that is in outer, but not in world. Take the highest of a few
+		pos, _, _ := MaxOf(Repeat(4, func() Vec { return rectStart(rng, outer, world) }), heightAt)
 
 		// target the center of the screen
 		dir := directionTo(pos, world.Center())
@@ -211,10 +222,9 @@ func walk(rng *rand.Rand, noise *fastnoiselite.FastNoiseLite, world Rect, stepSi
 				return
 			}
 
-			// check candidates in step direction
+			// check candidates in step direction, flow to the lowest one
 			angle, _, _ := MaxOf(slices.Values(angles), func(angle Rad) float64 {
-				pos := pos.Add(dir.Rotated(angle).Normalized().Mulf(lookAhead))
-				return noise.GetNoise2D(F(pos.X), F(pos.Y))
+				return -heightAt(pos.Add(dir.Rotated(angle).Normalized().Mulf(lookAhead)))
 			})
 
 			// calculate new direction
@@ -279,7 +289,7 @@ func linesToVecs(lines []Line) []Vec {
 	return points
 }
 
-func verticesToLines(vertices []ebiten.Vertex, indices []uint16) []Line {
+func verticesToLines(vertices []Vertex, indices []uint16) []Line {
 	if len(indices)%3 != 0 {
 		panic("number of indices must be dividable by three")
 	}
@@ -0,0 +1,117 @@
+//go:build !headless
+
+package main
+
+import (
+	"fmt"
+	. "github.com/quasilyte/gmath"
+	"math"
+	"time"
+)
+
+// time available in a time attack level, depending on the number of stations
+const timeAttackBaseTime = 30 * time.Second
+const timeAttackTimePerStation = 5 * time.Second
+
+// bonus score for every second left on the clock
+const timeAttackBonusPerSecond = 10
+
+func timeAttackLimit(stationCount int) time.Duration {
+	return timeAttackBaseTime + time.Duration(stationCount)*timeAttackTimePerStation
+}
+
+// isTimeAttack checks if the clock is ticking. Time attack is only played alone.
+func (g *Game) isTimeAttack() bool {
+	return g.timeAttack && !g.match.Turn.IsMultiplayer()
+}
+
+// startClock starts the countdown once the city generation has finished
+func (g *Game) startClock(stationCount int) {
+	if !g.isTimeAttack() {
+		return
+	}
+
+	g.timeRemaining = timeAttackLimit(stationCount)
+	g.clockRunning = true
+}
+
+// updateClock runs the countdown. It is paused while a dialog is shown.
+func (g *Game) updateClock(dt time.Duration) {
+	if !g.clockRunning || g.won || g.lost {
+		return
+	}
+
+	g.timeRemaining = max(0, g.timeRemaining-dt)
+
+	if g.timeRemaining > 0 {
+		return
+	}
+
+	g.clockRunning = false
+	g.lost = true
+	g.finishLevel()
+
+	g.audio.Play(g.audio.Lose)
+
+	g.dialogStack.Push(Dialog{
+		Id:    "timeout",
+		Modal: true,
+		Texts: []Text{
+			{
+				Face:  Font24,
+				Text:  "The last train has left the station",
+				Color: DarkTextColor,
+			},
+
+			{
+				Face:   Font16,
+				Text:   "Time’s up, I’m afraid! The villagers waited on the platform as long as they could,",
+				Color:  DarkTextColor,
+				Offset: Vec{Y: 8},
+			},
+
+			{
+				Face:  Font16,
+				Text:  fmt.Sprintf("but %d of %d stations are still without a train. A quicker hand on the", g.player().Stats.StationsTotal-g.player().Stats.StationsConnected, g.player().Stats.StationsTotal),
+				Color: DarkTextColor,
+			},
+
+			{
+				Face:  Font16,
+				Text:  "drawing board and you’ll have them all aboard in no time.",
+				Color: DarkTextColor,
+			},
+		},
+
+		Buttons: []*Button{
+			NewButton("Report", HudButtonColors).WithAutoSize().WithOnClick(g.showResults),
+
+			NewButton("Have another go", AcceptButtonColors).WithAutoSize().WithOnClick(func() {
+				g.resetOnUpdate = &ResetOnUpdate{
+					NextSeed: g.seed,
+				}
+			}),
+
+			NewButton("Onwards!", AcceptButtonColors).WithAutoSize().WithOnClick(func() {
+				g.resetOnUpdate = &ResetOnUpdate{
+					NextSeed: g.nextSeed(g.isSimple),
+				}
+			}),
+		},
+	})
+}
+
+// timeBonus converts the remaining seconds into score
+func (g *Game) timeBonus() int {
+	return int(g.timeRemaining.Seconds()) * timeAttackBonusPerSecond
+}
+
+func (g *Game) leaderboardNamespace() string {
+	return iff(g.isTimeAttack(), LeaderboardTimeAttack, LeaderboardClassic)
+}
+
+func formatCountdown(remaining time.Duration) string {
+	// only show zero once the time is actually up
+	secs := int(math.Ceil(remaining.Seconds()))
+	return fmt.Sprintf("%d:%02d", secs/60, secs%60)
+}
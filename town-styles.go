@@ -0,0 +1,118 @@
+package main
+
+import (
+	"github.com/furui/fastnoiselite-go"
+	. "github.com/quasilyte/gmath"
+	"math"
+)
+
+// TownStyle decides how the local streets of a village are laid out
+type TownStyle uint8
+
+const (
+	// winding streets following the population, the original look
+	TownStyleOrganic TownStyle = iota
+
+	// roman style, streets run in a rectangular grid
+	TownStyleGrid
+
+	// short dead-end streets on both sides of the highway
+	TownStyleRibbon
+
+	// streets run towards the market square and in rings around it
+	TownStyleRadial
+
+	townStyleCount
+)
+
+func (s TownStyle) String() string {
+	switch s {
+	case TownStyleGrid:
+		return "grid"
+	case TownStyleRibbon:
+		return "ribbon"
+	case TownStyleRadial:
+		return "radial"
+	default:
+		return "organic"
+	}
+}
+
+// newStyleNoise creates the noise that splits the world into areas of the same town style.
+// Cellular noise returns a constant value per cell, each cell is a few villages large.
+func newStyleNoise(seed int32) *fastnoiselite.FastNoiseLite {
+	noise := fastnoiselite.NewNoise()
+	noise.SetNoiseType(fastnoiselite.NoiseTypeCellular)
+	noise.CellularReturnType = fastnoiselite.CellularReturnTypeCellValue
+	noise.Seed = seed
+	noise.Frequency = 0.00015
+
+	return noise
+}
+
+// styleValueAt samples the style noise, returns a value in [0, 1)
+func (gen *StreetGenerator) styleValueAt(point Vec) float64 {
+	value := gen.styleNoise.GetNoise2D(fastnoiselite.FNLfloat(point.X), fastnoiselite.FNLfloat(point.Y))
+	return Clamp((value+1)/2, 0, 0.9999)
+}
+
+// StyleAt returns the town style of the area around the given point
+func (gen *StreetGenerator) StyleAt(point Vec) TownStyle {
+	return TownStyle(gen.styleValueAt(point) * float64(townStyleCount))
+}
+
+// styledAngle returns the direction of the next local street segment of a town
+func (gen *StreetGenerator) styledAngle(pending PendingSegment) Rad {
+	switch pending.Style {
+	case TownStyleGrid:
+		// the style noise is constant within a style cell, grid towns of the same
+		// cell share the orientation of their grid, other cells are rotated differently
+		gridAngle := Rad(gen.styleValueAt(pending.Origin) * math.Pi * 8)
+		return snapAngle(pending.Angle, gridAngle)
+
+	case TownStyleRadial:
+		if pending.Point.DistanceSquaredTo(pending.Origin) < 1 {
+			return pending.Angle
+		}
+
+		// either away from the market square, or in a ring around it
+		return snapAngle(pending.Angle, pending.Origin.AngleToPoint(pending.Point))
+
+	default:
+		return pending.Angle
+	}
+}
+
+// townCenterNear walks uphill on the population noise to find the center of the town,
+// roughly the same for all streets branching off the highway into the same town
+func (gen *StreetGenerator) townCenterNear(point Vec) Vec {
+	const stepSize = 100
+
+	for range 64 {
+		best, bestValue := point, gen.PopulationAt(point)
+
+		for idx := range 8 {
+			candidate := point.Add(Vec{X: stepSize}.Rotated(Rad(idx) * math.Pi / 4))
+			if value := gen.PopulationAt(candidate); value > bestValue {
+				best, bestValue = candidate, value
+			}
+		}
+
+		if best == point {
+			// reached the peak
+			break
+		}
+
+		point = best
+	}
+
+	return point
+}
+
+// snapAngle snaps the angle to the nearest multiple of 90 degree relative to the reference angle
+func snapAngle(angle, reference Rad) Rad {
+	const quarter = math.Pi / 2
+
+	steps := math.Round(float64(angle-reference) / quarter)
+	return reference + Rad(steps*quarter)
+}
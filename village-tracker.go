@@ -0,0 +1,179 @@
+package main
+
+import (
+	. "github.com/quasilyte/gmath"
+	"slices"
+	"time"
+)
+
+// VillageTracker clusters the streets while they are generated. Each new street is
+// joined with its neighbours using a union-find, the resulting clusters are the same
+// as the ones clusterStreets calculates once all streets are known.
+type VillageTracker struct {
+	distThreshold float64
+	minSegments   int
+
+	// the streets seen so far, without highways
+	grid Grid[*Segment]
+	uf   *UnionFind[*Segment]
+
+	// names of the clusters by their key
+	names map[int]string
+
+	// number of segments of the street generator we have already looked at
+	consumed int
+
+	// clusters by their root in the union-find
+	clusters map[*Segment]*trackedCluster
+}
+
+type trackedCluster struct {
+	// generation index of the oldest street, names the village
+	Key      int
+	Segments []*Segment
+
+	// size of the cluster when it last changed notably
+	stableSize int
+	changed    time.Time
+
+	// cached hull, reset whenever the cluster grows
+	hull []Vec
+}
+
+// VillagePreview is a village that is still growing
+type VillagePreview struct {
+	Name   string
+	Hull   []Vec
+	Center Vec
+
+	// time the village last grew notably
+	Changed time.Time
+}
+
+func NewVillageTracker(config GenerationConfig) *VillageTracker {
+	return &VillageTracker{
+		distThreshold: config.ClusterDistance,
+		minSegments:   config.MinClusterSegments,
+		grid:          NewGrid[*Segment](vecSplat(50), nil),
+		uf:            NewUnionFind[*Segment](nil),
+		names:         map[int]string{},
+		clusters:      map[*Segment]*trackedCluster{},
+	}
+}
+
+// Update adds the segments generated since the last call. The segments
+// must be passed in the order they were generated in.
+func (vt *VillageTracker) Update(segments []*Segment) {
+	now := time.Now()
+
+	for idx := vt.consumed; idx < len(segments); idx++ {
+		vt.add(segments[idx], idx, now)
+	}
+
+	vt.consumed = len(segments)
+}
+
+func (vt *VillageTracker) add(segment *Segment, idx int, now time.Time) {
+	if segment.Type == StreetTypeHighway {
+		// highways connect villages, they are not part of any cluster
+		return
+	}
+
+	vt.uf.Add(segment)
+
+	vt.clusters[segment] = &trackedCluster{
+		Key:      idx,
+		Segments: []*Segment{segment},
+		changed:  now,
+	}
+
+	bbox := segment.BBox()
+	bbox.Min = bbox.Min.Sub(vecSplat(vt.distThreshold))
+	bbox.Max = bbox.Max.Add(vecSplat(vt.distThreshold))
+
+	for candidate := range vt.grid.Candidates(bbox) {
+		if segment.DistanceToOther(candidate.Line) <= vt.distThreshold {
+			vt.union(segment, candidate, now)
+		}
+	}
+
+	vt.grid.Insert(segment)
+}
+
+func (vt *VillageTracker) union(a, b *Segment, now time.Time) {
+	rootA, rootB := vt.uf.Find(a), vt.uf.Find(b)
+	if rootA == rootB {
+		return
+	}
+
+	// merge the smaller cluster into the larger one
+	large, small := vt.clusters[rootA], vt.clusters[rootB]
+	if len(large.Segments) < len(small.Segments) {
+		rootA, rootB = rootB, rootA
+		large, small = small, large
+	}
+
+	vt.uf.Union(rootA, rootB)
+	delete(vt.clusters, rootB)
+
+	large.Key = min(large.Key, small.Key)
+	large.Segments = append(large.Segments, small.Segments...)
+	large.hull = nil
+
+	// small additions do not count as a change, the village is considered stable
+	if len(large.Segments) > large.stableSize*11/10 {
+		large.stableSize = len(large.Segments)
+		large.changed = now
+	}
+}
+
+// Clusters returns the clusters in the same order as clusterStreets does
+func (vt *VillageTracker) Clusters(grid Grid[*Segment]) [][]*Segment {
+	return componentsOf(streetsInGridOrder(grid), vt.uf)
+}
+
+// Preview returns the clusters that are large enough to become a village.
+// The hulls are convex, the final villages follow their streets more closely.
+func (vt *VillageTracker) Preview(names *NameGenerator) []VillagePreview {
+	var clusters []*trackedCluster
+	for _, cluster := range vt.clusters {
+		if len(cluster.Segments) > vt.minSegments {
+			clusters = append(clusters, cluster)
+		}
+	}
+
+	// keep a stable order, labels might overlap
+	slices.SortFunc(clusters, func(a, b *trackedCluster) int { return a.Key - b.Key })
+
+	var previews []VillagePreview
+
+	for _, cluster := range clusters {
+		if cluster.hull == nil {
+			cluster.hull = ConvexHull(pointsOf(cluster.Segments))
+		}
+
+		if len(cluster.hull) < 3 {
+			continue
+		}
+
+		var center Vec
+		for _, point := range cluster.hull {
+			center = center.Add(point)
+		}
+
+		name, ok := vt.names[cluster.Key]
+		if !ok {
+			name = names.PeekFor(uint64(cluster.Key))
+			vt.names[cluster.Key] = name
+		}
+
+		previews = append(previews, VillagePreview{
+			Name:    name,
+			Hull:    cluster.hull,
+			Center:  center.Mulf(1 / float64(len(cluster.hull))),
+			Changed: cluster.changed,
+		})
+	}
+
+	return previews
+}
@@ -0,0 +1,108 @@
+//go:build !headless
+
+package main
+
+import (
+	"fmt"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	. "github.com/quasilyte/gmath"
+	"image/color"
+)
+
+type DrawVillageBoundsOptions struct {
+	ToScreen    ebiten.GeoM
+	StrokeWidth float64
+	StrokeColor color.Color
+	FillColor   color.Color
+}
+
+func DrawVillageBounds(target *ebiten.Image, village *Village, opts DrawVillageBoundsOptions) {
+	path := pathOf(village.Hull, true)
+
+	_, _, _, fillAlpha := opts.FillColor.RGBA()
+	if fillAlpha > 0 {
+		FillPath(target, path, opts.ToScreen, opts.FillColor)
+	}
+
+	if opts.StrokeWidth > 0 {
+		StrokePath(target, path, opts.ToScreen, opts.StrokeColor, &vector.StrokeOptions{
+			Width:    float32(opts.StrokeWidth),
+			LineJoin: vector.LineJoinRound,
+			LineCap:  vector.LineCapSquare,
+		})
+	}
+}
+
+func (g *Game) drawVillageTooltip(target *ebiten.Image, pos Vec, village *Village) {
+	dialog := Dialog{
+		Padding: vecSplat(16),
+		Texts: []Text{
+			{
+				Text:  village.Name,
+				Face:  Font24,
+				Color: DarkTextColor,
+			},
+			{
+				Text:  fmt.Sprintf("Population: %d", village.PopulationCount),
+				Face:  Font16,
+				Color: DarkTextColor,
+			},
+		},
+	}
+
+	var noSpace bool
+	for line := range textwrap(village.FunFact) {
+		dialog.Texts = append(dialog.Texts, Text{
+			Text:   line,
+			Face:   Font16,
+			Color:  DarkTextColor,
+			Offset: Vec{Y: iff(noSpace, 0.0, 8)},
+		})
+
+		noSpace = true
+	}
+
+	size, _ := dialog.Measure()
+
+	if int(pos.X) > imageWidth(target)*3/4 {
+		// anchor tooltip top right corner of the dialog
+		pos = pos.Add(Vec{X: -size.X - 16, Y: 24})
+	} else {
+		// anchor tooltip at the top left corner
+		pos = pos.Add(Vec{X: 16, Y: 24})
+	}
+
+	if pos.Y+size.Y > imageSizeOf(target).Y {
+		pos.Y -= size.Y
+	}
+
+	dialog.DrawAt(target, pos)
+}
+
+func DrawWindow(target *ebiten.Image, pos Vec, size Vec) {
+	posShadow := pos.Add(vecSplat(4))
+	DrawRoundRect(target, posShadow, size, ShadowColor)
+
+	DrawRoundRect(target, pos, size, TooltipColor)
+}
+
+func pathOf(points []Vec, close bool) vector.Path {
+	var path vector.Path
+
+	if len(points) < 2 {
+		return path
+	}
+
+	path.MoveTo(float32(points[0].X), float32(points[0].Y))
+
+	for _, point := range points[1:] {
+		path.LineTo(float32(point.X), float32(point.Y))
+	}
+
+	if close {
+		path.Close()
+	}
+
+	return path
+}
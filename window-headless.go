@@ -0,0 +1,11 @@
+//go:build headless
+
+package main
+
+import "log"
+
+// runGame fails, the headless build has no window. It only
+// exports maps and plays bots, e.g. on a build server.
+func runGame(LaunchOptions) {
+	log.Fatal("built without a window, only -headless is supported")
+}
@@ -0,0 +1,90 @@
+//go:build !headless
+
+package main
+
+import (
+	"fmt"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/oliverbestmann/union-station/assets"
+	"log"
+)
+
+// runGame opens the window and plays until it is closed
+func runGame(options LaunchOptions) {
+	const windowScale = 2
+
+	screenWidth, screenHeight := 800, 480
+
+	// ensure we have an audio context
+	AudioContext()
+
+	game := &Loader[Audio]{
+		// load audio task
+		Promise: AsyncTask(func(yield func(string)) Audio {
+			yield("Loading audio data")
+			buttonPress := assets.ButtonPress()
+			buttonHover := assets.ButtonHover()
+			win := assets.WinSound()
+			lose := assets.LoseSound()
+
+			return Audio{
+				Songs:       assets.Songs(),
+				ButtonPress: Samples(buttonPress),
+				ButtonHover: Samples(buttonHover),
+				Win:         Samples(win),
+				Lose:        Samples(lose),
+			}
+		}),
+
+		LoadingScreen: &TheLoadingScreen{
+			now: TimeOrigin,
+		},
+
+		Next: func(audio Audio) ebiten.Game {
+			game := &Game{
+				audio:  audio,
+				seed:   options.Seed,
+				config: options.Config,
+				edits:  options.Edits,
+			}
+
+			if options.LevelFile != "" {
+				game.levelFile = &LevelFileRef{Path: options.LevelFile, Seed: options.Seed, Config: options.Config}
+			}
+
+			if options.Bot != "" {
+				if agent, ok := AgentByName(options.Bot); ok {
+					game.autopilot = agent
+				} else {
+					fmt.Printf("[err] unknown bot %q, expected one of %v\n", options.Bot, AgentNames)
+				}
+			}
+
+			if options.Versus != "" {
+				if _, ok := AgentByName(options.Versus); ok {
+					game.versusBot = options.Versus
+				} else {
+					fmt.Printf("[err] unknown versus bot %q, expected one of %v\n", options.Versus, AgentNames)
+				}
+			}
+
+			if options.Relay != "" {
+				game.connectRelay(options.Relay, options.Lobby, options.Seed)
+			}
+
+			return game
+		},
+	}
+
+	// Specify the window size as you like. Here, a doubled size is specified.
+	ebiten.SetWindowSize(screenWidth*windowScale, screenHeight*windowScale)
+	ebiten.SetWindowTitle("Union Station")
+	ebiten.SetVsyncEnabled(true)
+	ebiten.SetTPS(ebiten.SyncWithFPS)
+	ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
+
+	// Call ebiten.RunGame to start your game loop.
+	if err := ebiten.RunGame(game); err != nil {
+		log.Fatal(err)
+	}
+}